@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const adminTimeout = 10 * time.Second
+
+// CreateDatabase creates empty database, admin must be superuser config.
+func CreateDatabase(admin Config, name string) error {
+	return withConnection(admin, maintenanceDatabase, func(conn *sql.DB) error {
+		_, err := conn.Exec("CREATE DATABASE " + quoteIdentifier(name))
+		return err
+	})
+}
+
+// DropDatabase terminates all connections to database and drops it.
+func DropDatabase(admin Config, name string) error {
+	return withConnection(admin, maintenanceDatabase, func(conn *sql.DB) error {
+		_, err := conn.Exec(`SELECT pg_terminate_backend(pid) FROM pg_stat_activity
+			WHERE datname = $1 AND pid <> pg_backend_pid()`, name)
+		if err != nil {
+			return fmt.Errorf("terminate connections error: %w", err)
+		}
+		_, err = conn.Exec("DROP DATABASE IF EXISTS " + quoteIdentifier(name))
+		return err
+	})
+}
+
+// CreateRole creates login role and grants privileges in admin.DbName database.
+// Each grant is a GRANT statement body without 'GRANT' and 'TO role' parts,
+// e.g. "SELECT, INSERT ON ALL TABLES IN SCHEMA public".
+func CreateRole(admin Config, name, password string, grants ...string) error {
+	return withConnection(admin, admin.DbName, func(conn *sql.DB) error {
+		_, err := conn.Exec("CREATE ROLE " + quoteIdentifier(name) + " LOGIN PASSWORD " + quoteLiteral(password))
+		if err != nil {
+			return err
+		}
+		for _, grant := range grants {
+			_, err = conn.Exec("GRANT " + grant + " TO " + quoteIdentifier(name))
+			if err != nil {
+				return fmt.Errorf("grant %s error: %w", grant, err)
+			}
+		}
+		return nil
+	})
+}
+
+// DropRole revokes role privileges in admin.DbName database and drops it.
+func DropRole(admin Config, name string) error {
+	return withConnection(admin, admin.DbName, func(conn *sql.DB) error {
+		_, err := conn.Exec(`SELECT pg_terminate_backend(pid) FROM pg_stat_activity
+			WHERE usename = $1 AND pid <> pg_backend_pid()`, name)
+		if err != nil {
+			return fmt.Errorf("terminate connections error: %w", err)
+		}
+		_, err = conn.Exec("DROP OWNED BY " + quoteIdentifier(name))
+		if err != nil {
+			return err
+		}
+		_, err = conn.Exec("DROP ROLE " + quoteIdentifier(name))
+		return err
+	})
+}
+
+func withConnection(conf Config, database string, f func(conn *sql.DB) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), adminTimeout)
+	defer cancel()
+
+	conf.DbName = database
+	conn, err := (&ClientPg{}).newConnection(ctx, conf)
+	if err != nil {
+		return fmt.Errorf("admin connection error: %w", err)
+	}
+	defer conn.Close()
+
+	return f(conn.DB)
+}
+
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// TerminateConnections terminates all application connections to admin.DbName database,
+// helper connections are kept. Returns number of terminated connections.
+func TerminateConnections(admin Config) (int, error) {
+	var n int
+	err := withConnection(admin, maintenanceDatabase, func(conn *sql.DB) error {
+		return conn.QueryRow(`SELECT count(pg_terminate_backend(pid)) FROM pg_stat_activity
+			WHERE datname = $1 AND application_name <> $2 AND pid <> pg_backend_pid()`,
+			admin.DbName, applicationName,
+		).Scan(&n)
+	})
+	return n, err
+}
@@ -0,0 +1,251 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const eventuallyPollInterval = 100 * time.Millisecond
+
+// numericText matches finite NUMERIC values, which drivers return as text: lib/pq as []byte, pgx as string.
+var numericText = regexp.MustCompile(`^(-?[0-9]+)(?:\.([0-9]+))?$`)
+
+// AssertRowCount fails the test if table does not contain exactly n rows matching where clause.
+// Empty where matches all rows, args are passed as query placeholders ($1, $2...).
+func (p *ClientPg) AssertRowCount(table, where string, n int, args ...any) {
+	p.t.Helper()
+
+	count, err := p.rowCount(table, where, args...)
+	if err != nil {
+		p.t.Errorf("AssertRowCount: %s", err)
+		return
+	}
+	if count != n {
+		p.t.Errorf("AssertRowCount: table %s where %q: expected %d rows, actual %d", table, where, n, count)
+	}
+}
+
+// AssertRow fails the test if the single row matching where clause differs from expected.
+// Only columns present in expected are compared.
+func (p *ClientPg) AssertRow(table, where string, expected map[string]any, args ...any) {
+	p.t.Helper()
+
+	actual, err := p.row(table, where, args...)
+	if err != nil {
+		p.t.Errorf("AssertRow: %s", err)
+		return
+	}
+	if diff := rowDiff(expected, actual); diff != "" {
+		p.t.Errorf("AssertRow: table %s where %q mismatch (-expected +actual):\n%s", table, where, diff)
+	}
+}
+
+// EventuallyRow polls the database until the single row matching where clause equals expected
+// or timeout expires. Useful for side effects of asynchronous processing (e.g. kafka consumers).
+func (p *ClientPg) EventuallyRow(timeout time.Duration, table, where string, expected map[string]any, args ...any) {
+	p.t.Helper()
+
+	if timeout == 0 {
+		timeout = time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var lastErr error
+	var lastDiff string
+	for {
+		actual, err := p.row(table, where, args...)
+		lastErr = err
+		if err == nil {
+			lastDiff = rowDiff(expected, actual)
+			if lastDiff == "" {
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if lastErr != nil {
+				p.t.Errorf("EventuallyRow: timeout %s: %s", timeout, lastErr)
+				return
+			}
+			p.t.Errorf("EventuallyRow: timeout %s: table %s where %q mismatch (-expected +actual):\n%s",
+				timeout, table, where, lastDiff)
+			return
+		case <-time.After(eventuallyPollInterval):
+		}
+	}
+}
+
+func (p *ClientPg) rowCount(table, where string, args ...any) (int, error) {
+	query := "SELECT count(*) FROM " + table + whereClause(where)
+
+	var count int
+	err := p.conn.QueryRow(query, args...).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count query error: %w", err)
+	}
+	return count, nil
+}
+
+func (p *ClientPg) row(table, where string, args ...any) (map[string]any, error) {
+	query := "SELECT * FROM " + table + whereClause(where) + " LIMIT 2"
+
+	rows, err := p.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("select query error: %w", err)
+	}
+	defer rows.Close()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, fmt.Errorf("rows column types error: %w", err)
+	}
+	result, err := scanRows(rows)
+	if err != nil {
+		return nil, err
+	}
+	markNumeric(result, numericColumns(types))
+
+	switch len(result) {
+	case 0:
+		return nil, fmt.Errorf("table %s where %q: no rows found", table, where)
+	case 1:
+		return result[0], nil
+	default:
+		return nil, fmt.Errorf("table %s where %q: more than one row found", table, where)
+	}
+}
+
+func whereClause(where string) string {
+	if where == "" {
+		return ""
+	}
+	return " WHERE " + where
+}
+
+// scanRows reads all rows to column:value maps.
+func scanRows(rows *sql.Rows) ([]map[string]any, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("rows columns error: %w", err)
+	}
+
+	var result []map[string]any
+	for rows.Next() {
+		values := make([]any, len(columns))
+		pointers := make([]any, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		err = rows.Scan(pointers...)
+		if err != nil {
+			return nil, fmt.Errorf("rows scan error: %w", err)
+		}
+
+		row := make(map[string]any, len(columns))
+		for i, column := range columns {
+			row[column] = values[i]
+		}
+		result = append(result, row)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return result, nil
+}
+
+// numeric is NUMERIC column value, compared with expected numbers by value.
+type numeric string
+
+// numericColumns returns names of NUMERIC columns, values of which are marked with markNumeric.
+func numericColumns(types []*sql.ColumnType) []string {
+	var columns []string
+	for _, columnType := range types {
+		if columnType.DatabaseTypeName() == "NUMERIC" {
+			columns = append(columns, columnType.Name())
+		}
+	}
+	return columns
+}
+
+// markNumeric converts text values of columns to numeric.
+func markNumeric(result []map[string]any, columns []string) {
+	for _, column := range columns {
+		for _, row := range result {
+			switch val := row[column].(type) {
+			case []byte:
+				row[column] = numeric(val)
+			case string:
+				row[column] = numeric(val)
+			}
+		}
+	}
+}
+
+// rowDiff returns human-readable difference of expected columns, empty string if rows are equal.
+func rowDiff(expected, actual map[string]any) string {
+	columns := make([]string, 0, len(expected))
+	for column := range expected {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	var b strings.Builder
+	for _, column := range columns {
+		a, ok := actual[column]
+		if !ok {
+			fmt.Fprintf(&b, "  %s: column not found\n", column)
+			continue
+		}
+		e := formatValue(expected[column])
+		if e == formatValue(a) {
+			continue
+		}
+		fmt.Fprintf(&b, "- %s: %s\n+ %s: %s\n", column, e, column, formatValue(a))
+	}
+	return b.String()
+}
+
+// formatValue unifies database and go values representation, so int(1) equals int64(1),
+// []byte("a") equals "a" and NUMERIC "100.50" or json.Number("100.50") equals 100.5.
+func formatValue(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return "NULL"
+	case json.Number:
+		return formatValue(numeric(val))
+	case numeric:
+		if match := numericText.FindStringSubmatch(string(val)); match != nil {
+			return normalizeNumeric(match)
+		}
+		// NaN and Infinity
+		return string(val)
+	case []byte:
+		return fmt.Sprintf("%q", string(val))
+	case string:
+		return fmt.Sprintf("%q", val)
+	case time.Time:
+		return val.UTC().Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// normalizeNumeric removes insignificant zeros of fractional part, like fmt prints floats.
+func normalizeNumeric(match []string) string {
+	fraction := strings.TrimRight(match[2], "0")
+	if fraction == "" {
+		if match[1] == "-0" {
+			return "0"
+		}
+		return match[1]
+	}
+	return match[1] + "." + fraction
+}
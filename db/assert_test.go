@@ -0,0 +1,82 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		expected any
+		actual   any
+		equal    bool
+	}{
+		{1, int64(1), true},
+		{"a", []byte("a"), true},
+		{"a", "a", true},
+		{nil, nil, true},
+		{nil, "NULL", false},
+		{100.5, numeric("100.50"), true},
+		{100, numeric("100.00"), true},
+		{100, numeric("100"), true},
+		{-0.5, numeric("-0.50"), true},
+		{0, numeric("-0.00"), true},
+		{0.25, numeric("0.250"), true},
+		{100.5, numeric("100.51"), false},
+		{"100.50", numeric("100.50"), false},
+		{"NaN", numeric("NaN"), false},
+		// text and bytea columns are not numbers even if they look like ones
+		{100.5, "100.50", false},
+		{100.5, []byte("100.50"), false},
+		{
+			time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("", 3600)),
+			time.Date(2024, 1, 2, 2, 4, 5, 0, time.UTC),
+			true,
+		},
+	}
+	for _, tt := range tests {
+		e, a := formatValue(tt.expected), formatValue(tt.actual)
+		if (e == a) != tt.equal {
+			t.Errorf("formatValue(%#v) = %s, formatValue(%#v) = %s, expected equal %t", tt.expected, e, tt.actual, a, tt.equal)
+		}
+	}
+}
+
+func TestNormalizeNumeric(t *testing.T) {
+	tests := map[string]string{
+		"100.50":                  "100.5",
+		"100.00":                  "100",
+		"100":                     "100",
+		"-1.10":                   "-1.1",
+		"-0.0":                    "0",
+		"0.000100":                "0.0001",
+		"12345678901234567890.10": "12345678901234567890.1",
+	}
+	for value, expected := range tests {
+		match := numericText.FindStringSubmatch(value)
+		if match == nil {
+			t.Errorf("%s is not matched as numeric", value)
+			continue
+		}
+		if actual := normalizeNumeric(match); actual != expected {
+			t.Errorf("normalizeNumeric(%s) = %s, expected %s", value, actual, expected)
+		}
+	}
+}
+
+func TestMarkNumeric(t *testing.T) {
+	rows := []map[string]any{
+		{"amount": []byte("1.50"), "price": "2.50", "name": "1.50"},
+		{"amount": nil, "price": "3", "name": "x"},
+	}
+	markNumeric(rows, []string{"amount", "price"})
+
+	expected := []map[string]any{
+		{"amount": numeric("1.50"), "price": numeric("2.50"), "name": "1.50"},
+		{"amount": nil, "price": numeric("3"), "name": "x"},
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("markNumeric = %v, expected %v", rows, expected)
+	}
+}
@@ -0,0 +1,248 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	captureTimeout      = 5 * time.Second
+	capturePollInterval = 50 * time.Millisecond
+
+	// csvlog columns, stable since postgres 9
+	csvSeverityColumn    = 11
+	csvMessageColumn     = 13
+	csvApplicationColumn = 22
+)
+
+// StatementLog returns postgres csvlog with all executed statements starting at byte offset.
+type StatementLog func(ctx context.Context, offset int64) (io.ReadCloser, error)
+
+// QueryCapture collects statements executed by the application since capture start.
+// Statements of helper connections and transaction control or session statements
+// (BEGIN, COMMIT, SET...) are skipped. Capture is database wide,
+// so parallel tests sharing database affect each other.
+type QueryCapture struct {
+	t    *testing.T
+	conn *sql.DB
+	log  StatementLog
+	id   string
+
+	// log is read incrementally: offset of the first not parsed record
+	offset  int64
+	started bool
+	queries []string
+}
+
+func NewQueryCapture(t *testing.T, conn *sql.DB, log StatementLog) (*QueryCapture, error) {
+	c := &QueryCapture{
+		t:    t,
+		conn: conn,
+		log:  log,
+		id:   fmt.Sprintf("%d", time.Now().UnixNano()),
+	}
+
+	err := c.mark("start")
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Queries returns statements executed by the application since capture start.
+func (c *QueryCapture) Queries() []string {
+	c.t.Helper()
+
+	queries, err := c.collect()
+	if err != nil {
+		c.t.Errorf("QueryCapture Queries: %s", err)
+		return nil
+	}
+	return queries
+}
+
+// AssertMaxQueries fails the test if the application executed more than n statements since capture start.
+func (c *QueryCapture) AssertMaxQueries(n int) {
+	c.t.Helper()
+
+	queries, err := c.collect()
+	if err != nil {
+		c.t.Errorf("AssertMaxQueries: %s", err)
+		return
+	}
+	if len(queries) > n {
+		c.t.Errorf("AssertMaxQueries: expected no more than %d queries, actual %d:\n%s",
+			n, len(queries), strings.Join(queries, "\n"))
+	}
+}
+
+// AssertQueryCount fails the test if the number of statements containing substr differs from n.
+func (c *QueryCapture) AssertQueryCount(substr string, n int) {
+	c.t.Helper()
+
+	queries, err := c.collect()
+	if err != nil {
+		c.t.Errorf("AssertQueryCount: %s", err)
+		return
+	}
+
+	var count int
+	for _, q := range queries {
+		if strings.Contains(q, substr) {
+			count++
+		}
+	}
+	if count != n {
+		c.t.Errorf("AssertQueryCount: expected %d queries containing %q, actual %d:\n%s",
+			n, substr, count, strings.Join(queries, "\n"))
+	}
+}
+
+func (c *QueryCapture) collect() ([]string, error) {
+	end := fmt.Sprintf("end:%d", time.Now().UnixNano())
+	err := c.mark(end)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), captureTimeout)
+	defer cancel()
+
+	// log collector writes asynchronously, wait for end marker
+	for {
+		found, err := c.read(ctx, end)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			queries := make([]string, len(c.queries))
+			copy(queries, c.queries)
+			return queries, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, errors.New("capture end marker not found in statement log")
+		case <-time.After(capturePollInterval):
+		}
+	}
+}
+
+// read parses new records of statement log, collecting application statements after start marker.
+// Reports whether end marker is found, reading stops after it.
+func (c *QueryCapture) read(ctx context.Context, end string) (bool, error) {
+	rc, err := c.log(ctx, c.offset)
+	if err != nil {
+		return false, fmt.Errorf("statement log error: %w", err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return false, fmt.Errorf("statement log read error: %w", err)
+	}
+	return c.parse(data, end)
+}
+
+// parse parses csvlog data read at offset, advancing offset past parsed records.
+// The last record may be partially written, it is parsed again on the next read.
+func (c *QueryCapture) parse(data []byte, end string) (bool, error) {
+	data = data[:bytes.LastIndexByte(data, '\n')+1]
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+
+	startMarker, endMarker := c.marker("start"), c.marker(end)
+	base := c.offset
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return false, nil
+		}
+		// partially written record ends inside quoted multiline field,
+		// so reader reaches the end of data looking for closing quote
+		if errors.Is(err, csv.ErrQuote) && r.InputOffset() == int64(len(data)) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("statement log parse error: %w", err)
+		}
+		c.offset = base + r.InputOffset()
+
+		if len(record) <= csvApplicationColumn {
+			return false, fmt.Errorf("statement log: unexpected csvlog record of %d columns", len(record))
+		}
+		if record[csvSeverityColumn] != "LOG" {
+			continue
+		}
+
+		message := record[csvMessageColumn]
+		switch {
+		case strings.Contains(message, startMarker):
+			c.started = true
+			continue
+		case strings.Contains(message, endMarker):
+			return true, nil
+		case !c.started || record[csvApplicationColumn] == applicationName:
+			continue
+		}
+
+		if query, ok := statement(message); ok && !sessionStatement(query) {
+			c.queries = append(c.queries, query)
+		}
+	}
+}
+
+func (c *QueryCapture) mark(name string) error {
+	_, err := c.conn.Exec("SELECT '" + c.marker(name) + "'")
+	if err != nil {
+		return fmt.Errorf("capture marker error: %w", err)
+	}
+	return nil
+}
+
+func (c *QueryCapture) marker(name string) string {
+	return "testosteron:capture:" + c.id + ":" + name
+}
+
+// statement extracts query from 'statement: ...' (simple protocol)
+// and 'execute <name>: ...' (extended protocol) log messages.
+func statement(message string) (string, bool) {
+	if query, ok := strings.CutPrefix(message, "statement: "); ok {
+		return query, true
+	}
+	if rest, ok := strings.CutPrefix(message, "execute "); ok {
+		if _, query, ok := strings.Cut(rest, ": "); ok {
+			return query, true
+		}
+	}
+	return "", false
+}
+
+// sessionStatements are transaction control and session statements, not counted as queries.
+var sessionStatements = []string{
+	"BEGIN", "START", "COMMIT", "END", "ROLLBACK", "ABORT", "SAVEPOINT", "RELEASE",
+	"SET", "RESET", "SHOW", "DISCARD", "DEALLOCATE",
+}
+
+func sessionStatement(query string) bool {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return true
+	}
+	keyword := strings.ToUpper(strings.TrimSuffix(fields[0], ";"))
+	for _, s := range sessionStatements {
+		if keyword == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package db
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// logRecord returns csvlog line with severity, message and application name columns set.
+func logRecord(t *testing.T, application, severity, message string) string {
+	t.Helper()
+
+	record := make([]string, csvApplicationColumn+2)
+	record[csvSeverityColumn] = severity
+	record[csvMessageColumn] = message
+	record[csvApplicationColumn] = application
+
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	if err := w.Write(record); err != nil {
+		t.Fatalf("csv write error: %s", err)
+	}
+	w.Flush()
+	return b.String()
+}
+
+func TestQueryCaptureParse(t *testing.T) {
+	c := &QueryCapture{id: "1"}
+	multiline := "SELECT id\nFROM orders"
+
+	log := logRecord(t, "app", "LOG", "statement: SELECT 'before start'") +
+		logRecord(t, "", "LOG", "statement: SELECT '"+c.marker("start")+"'") +
+		logRecord(t, "app", "LOG", "statement: SELECT 1") +
+		logRecord(t, applicationName, "LOG", "statement: SELECT 'helper'") +
+		logRecord(t, "app", "LOG", "statement: BEGIN") +
+		logRecord(t, "app", "LOG", "execute <unnamed>: SELECT * FROM orders WHERE id = $1") +
+		logRecord(t, "app", "ERROR", "relation \"missing\" does not exist") +
+		logRecord(t, "app", "LOG", "statement: "+multiline) +
+		logRecord(t, "app", "LOG", "statement: COMMIT") +
+		logRecord(t, "", "LOG", "statement: SELECT '"+c.marker("end:1")+"'")
+
+	// record with multiline statement is written partially
+	split := strings.Index(log, "\nFROM orders") + 1
+	found, err := c.parse([]byte(log[:split]), "end:1")
+	if err != nil || found {
+		t.Fatalf("partial log: expected not found without error, actual %t %v", found, err)
+	}
+	if start := strings.Index(log, logRecord(t, "app", "LOG", "statement: "+multiline)); c.offset != int64(start) {
+		t.Fatalf("partial log: expected offset %d at the start of partial record, actual %d", start, c.offset)
+	}
+
+	found, err = c.parse([]byte(log[c.offset:]), "end:1")
+	if err != nil || !found {
+		t.Fatalf("full log: expected end marker found without error, actual %t %v", found, err)
+	}
+
+	expected := []string{"SELECT 1", "SELECT * FROM orders WHERE id = $1", multiline}
+	if !reflect.DeepEqual(c.queries, expected) {
+		t.Errorf("queries = %q, expected %q", c.queries, expected)
+	}
+}
+
+func TestQueryCaptureParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"broken quote":  "a,\"b\"x,c\n" + logRecord(t, "app", "LOG", "statement: SELECT 1"),
+		"short record":  "a,b,c\n",
+		"partial short": "a,b\n\"c",
+	}
+	for name, log := range tests {
+		c := &QueryCapture{id: "1"}
+		if _, err := c.parse([]byte(log), "end"); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestStatement(t *testing.T) {
+	tests := []struct {
+		message string
+		query   string
+		ok      bool
+	}{
+		{"statement: SELECT 1", "SELECT 1", true},
+		{"execute <unnamed>: SELECT $1", "SELECT $1", true},
+		{"execute stmtcache_1: UPDATE orders SET status = $1", "UPDATE orders SET status = $1", true},
+		{"duration: 0.1 ms", "", false},
+		{"execute", "", false},
+	}
+	for _, tt := range tests {
+		query, ok := statement(tt.message)
+		if query != tt.query || ok != tt.ok {
+			t.Errorf("statement(%q) = %q, %t, expected %q, %t", tt.message, query, ok, tt.query, tt.ok)
+		}
+	}
+}
+
+func TestSessionStatement(t *testing.T) {
+	tests := map[string]bool{
+		"BEGIN":                         true,
+		"begin;":                        true,
+		"START TRANSACTION":             true,
+		"SET search_path = public":      true,
+		"  COMMIT":                      true,
+		"":                              true,
+		"SELECT 1":                      false,
+		"SETTINGS_TABLE":                false,
+		"INSERT INTO settings VALUES 1": false,
+	}
+	for query, expected := range tests {
+		if actual := sessionStatement(query); actual != expected {
+			t.Errorf("sessionStatement(%q) = %t, expected %t", query, actual, expected)
+		}
+	}
+}
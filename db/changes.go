@@ -0,0 +1,221 @@
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// changesPrefix starts names of audit tables, functions and triggers. Names are suffixed per client,
+// so parallel tests sharing database track changes independently.
+const changesPrefix = "testosteron_changes"
+
+// changesSeq makes tracking names unique within process, start time makes them unique across processes.
+var (
+	changesSeq   atomic.Int64
+	changesStart = strconv.FormatInt(time.Now().UnixNano(), 36)
+)
+
+// changeTracking holds names of client audit objects and tables tracked so far.
+type changeTracking struct {
+	table    string
+	function string
+	trigger  string
+	tables   map[string]struct{}
+}
+
+// Change is a single row change made while tracking.
+type Change struct {
+	Table     string         `json:"table"`
+	Operation string         `json:"operation"` // INSERT, UPDATE or DELETE
+	Old       map[string]any `json:"old,omitempty"`
+	New       map[string]any `json:"new,omitempty"`
+}
+
+// String formats change as a diff, only changed columns are shown for updates.
+func (c Change) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s", c.Operation, c.Table)
+
+	columns := make([]string, 0, len(c.Old)+len(c.New))
+	for column := range c.New {
+		columns = append(columns, column)
+	}
+	for column := range c.Old {
+		if _, ok := c.New[column]; !ok {
+			columns = append(columns, column)
+		}
+	}
+	sort.Strings(columns)
+
+	for _, column := range columns {
+		oldValue, hasOld := c.Old[column]
+		newValue, hasNew := c.New[column]
+		switch {
+		case hasOld && hasNew:
+			if formatValue(oldValue) != formatValue(newValue) {
+				fmt.Fprintf(&b, "\n- %s: %s\n+ %s: %s", column, formatValue(oldValue), column, formatValue(newValue))
+			}
+		case hasOld:
+			fmt.Fprintf(&b, "\n- %s: %s", column, formatValue(oldValue))
+		default:
+			fmt.Fprintf(&b, "\n+ %s: %s", column, formatValue(newValue))
+		}
+	}
+	return b.String()
+}
+
+// TrackChanges installs triggers recording inserts, updates and deletes of tables
+// until the end of the test. All user tables are tracked if none provided,
+// partitions are tracked by triggers of partitioned tables.
+// Repeated calls add tables to tracking, already tracked tables are skipped.
+func (p *ClientPg) TrackChanges(tables ...string) error {
+	if len(tables) == 0 {
+		all, err := p.listTables(false)
+		if err != nil {
+			return fmt.Errorf("track changes: %w", err)
+		}
+		tables = all
+	}
+
+	if p.changes == nil {
+		err := p.startTracking()
+		if err != nil {
+			return fmt.Errorf("track changes: %w", err)
+		}
+	}
+
+	for _, table := range tables {
+		if _, ok := p.changes.tables[table]; ok {
+			continue
+		}
+		_, err := p.conn.Exec(`CREATE TRIGGER ` + p.changes.trigger + ` AFTER INSERT OR UPDATE OR DELETE ON ` +
+			quoteQualified(table) + ` FOR EACH ROW EXECUTE PROCEDURE ` + p.changes.function + `()`)
+		if err != nil {
+			return fmt.Errorf("track changes: create trigger on %s error: %w", table, err)
+		}
+		p.changes.tables[table] = struct{}{}
+	}
+	return nil
+}
+
+// startTracking creates client audit table and trigger function, they are dropped in test cleanup.
+func (p *ClientPg) startTracking() error {
+	suffix := changesStart + "_" + strconv.FormatInt(changesSeq.Add(1), 10)
+	tracking := &changeTracking{
+		table:    changesPrefix + "_" + suffix,
+		function: changesPrefix + "_fn_" + suffix,
+		trigger:  changesPrefix + "_tr_" + suffix,
+		tables:   make(map[string]struct{}),
+	}
+
+	_, err := p.conn.Exec(`CREATE TABLE ` + tracking.table + ` (
+		id bigserial PRIMARY KEY,
+		table_name text NOT NULL,
+		operation text NOT NULL,
+		old_row jsonb,
+		new_row jsonb
+	)`)
+	if err != nil {
+		return fmt.Errorf("create table error: %w", err)
+	}
+
+	_, err = p.conn.Exec(`CREATE FUNCTION ` + tracking.function + `() RETURNS trigger AS $$
+	BEGIN
+		INSERT INTO ` + tracking.table + ` (table_name, operation, old_row, new_row) VALUES (
+			TG_TABLE_NAME,
+			TG_OP,
+			CASE WHEN TG_OP <> 'INSERT' THEN to_jsonb(OLD) END,
+			CASE WHEN TG_OP <> 'DELETE' THEN to_jsonb(NEW) END
+		);
+		RETURN NULL;
+	END;
+	$$ LANGUAGE plpgsql`)
+	if err != nil {
+		_, _ = p.conn.Exec(`DROP TABLE IF EXISTS ` + tracking.table)
+		return fmt.Errorf("create function error: %w", err)
+	}
+
+	p.changes = tracking
+	p.t.Cleanup(func() {
+		err := p.untrackChanges(tracking)
+		if err != nil {
+			p.t.Errorf("track changes cleanup error: %s", err)
+		}
+	})
+	return nil
+}
+
+// Changes returns all changes made to tracked tables in order.
+func (p *ClientPg) Changes() ([]Change, error) {
+	if p.changes == nil {
+		return nil, errors.New("changes are not tracked, call TrackChanges first")
+	}
+
+	rows, err := p.conn.Query(`SELECT table_name, operation, old_row, new_row FROM ` + p.changes.table + ` ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("select changes error: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []Change
+	for rows.Next() {
+		var c Change
+		var oldRow, newRow []byte
+		err = rows.Scan(&c.Table, &c.Operation, &oldRow, &newRow)
+		if err != nil {
+			return nil, fmt.Errorf("scan changes error: %w", err)
+		}
+		if c.Old, err = decodeRow(oldRow); err != nil {
+			return nil, fmt.Errorf("parse old row error: %w", err)
+		}
+		if c.New, err = decodeRow(newRow); err != nil {
+			return nil, fmt.Errorf("parse new row error: %w", err)
+		}
+		changes = append(changes, c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("select changes error: %w", err)
+	}
+	return changes, nil
+}
+
+// decodeRow decodes jsonb row, numbers are kept as json.Number so bigint values don't lose precision.
+func decodeRow(raw []byte) (map[string]any, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var row map[string]any
+	err := decoder.Decode(&row)
+	if err != nil {
+		return nil, err
+	}
+	return row, nil
+}
+
+func (p *ClientPg) untrackChanges(tracking *changeTracking) error {
+	for table := range tracking.tables {
+		_, err := p.conn.Exec(`DROP TRIGGER IF EXISTS ` + tracking.trigger + ` ON ` + quoteQualified(table))
+		if err != nil {
+			return fmt.Errorf("drop trigger on %s error: %w", table, err)
+		}
+	}
+	_, err := p.conn.Exec(`DROP FUNCTION IF EXISTS ` + tracking.function + `() CASCADE`)
+	if err != nil {
+		return fmt.Errorf("drop function error: %w", err)
+	}
+	_, err = p.conn.Exec(`DROP TABLE IF EXISTS ` + tracking.table)
+	if err != nil {
+		return fmt.Errorf("drop table error: %w", err)
+	}
+	return nil
+}
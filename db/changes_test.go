@@ -0,0 +1,40 @@
+package db
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDecodeRow(t *testing.T) {
+	row, err := decodeRow([]byte(`{"id": 9007199254740993, "amount": 100.50, "status": "paid", "note": null}`))
+	if err != nil {
+		t.Fatalf("decode error: %s", err)
+	}
+	if row["id"] != json.Number("9007199254740993") {
+		t.Errorf("id lost precision: %v", row["id"])
+	}
+	if formatValue(row["amount"]) != formatValue(100.5) {
+		t.Errorf("amount %v is not equal to 100.5", row["amount"])
+	}
+	if row["status"] != "paid" || row["note"] != nil {
+		t.Errorf("unexpected row %v", row)
+	}
+
+	row, err = decodeRow(nil)
+	if err != nil || row != nil {
+		t.Errorf("expected nil row for NULL, actual %v %v", row, err)
+	}
+}
+
+func TestChangeString(t *testing.T) {
+	c := Change{
+		Table:     "orders",
+		Operation: "UPDATE",
+		Old:       map[string]any{"id": json.Number("1"), "status": "new", "amount": json.Number("10.50")},
+		New:       map[string]any{"id": json.Number("1"), "status": "paid", "amount": json.Number("10.5")},
+	}
+	expected := "UPDATE orders\n- status: \"new\"\n+ status: \"paid\""
+	if actual := c.String(); actual != expected {
+		t.Errorf("String = %q, expected %q", actual, expected)
+	}
+}
@@ -2,9 +2,17 @@ package db
 
 import (
 	"fmt"
+	"net"
+	"net/url"
+	"strconv"
 )
 
-const postgresDriver = "postgres"
+const (
+	postgresDriver = "postgres"
+
+	// applicationName marks helper connections, so their statements are not captured.
+	applicationName = "testosteron"
+)
 
 type Config struct {
 	Host     string
@@ -14,8 +22,28 @@ type Config struct {
 	Password string
 }
 
+// String returns keyword/value DSN for lib/pq.
 func (c Config) String() string {
-	return fmt.Sprintf(
+	return c.dsn("")
+}
+
+// URL returns postgres:// connection URL, accepted by pgx and most other drivers.
+func (c Config) URL() string {
+	return c.url("")
+}
+
+// helperDSN and helperURL tag connections with applicationName, so application connections
+// made with String or URL are captured and terminated, while helper connections are not.
+func (c Config) helperDSN() string {
+	return c.dsn(applicationName)
+}
+
+func (c Config) helperURL() string {
+	return c.url(applicationName)
+}
+
+func (c Config) dsn(application string) string {
+	dsn := fmt.Sprintf(
 		"host=%s user=%s port=%d dbname=%s password=%s sslmode=disable binary_parameters=yes",
 		c.Host,
 		c.User,
@@ -23,4 +51,23 @@ func (c Config) String() string {
 		c.DbName,
 		c.Password,
 	)
+	if application != "" {
+		dsn += " application_name=" + application
+	}
+	return dsn
+}
+
+func (c Config) url(application string) string {
+	query := url.Values{"sslmode": {"disable"}}
+	if application != "" {
+		query.Set("application_name", application)
+	}
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.User, c.Password),
+		Host:     net.JoinHostPort(c.Host, strconv.Itoa(c.Port)),
+		Path:     "/" + c.DbName,
+		RawQuery: query.Encode(),
+	}
+	return u.String()
 }
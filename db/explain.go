@@ -0,0 +1,149 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// PlanNode is a node of EXPLAIN (FORMAT JSON) output.
+type PlanNode struct {
+	NodeType     string     `json:"Node Type"`
+	RelationName string     `json:"Relation Name,omitempty"`
+	Schema       string     `json:"Schema,omitempty"`
+	IndexName    string     `json:"Index Name,omitempty"`
+	TotalCost    float64    `json:"Total Cost"`
+	Plans        []PlanNode `json:"Plans,omitempty"`
+}
+
+// Plan is checked by rules of ExplainAssert.
+type Plan struct {
+	// Nodes of the plan made with sequential scans disabled, so scans show indexes planner can use.
+	Nodes []PlanNode
+	// Cost is total cost of the plan made with default settings, disabled scans would inflate it.
+	Cost float64
+}
+
+// PlanRule checks query plan, returns error describing violation.
+type PlanRule func(plan Plan) error
+
+// NoSeqScan forbids sequential scans on table, given by name or by schema.name.
+// Table must be scanned by the query, so misspelled name fails the rule.
+func NoSeqScan(table string) PlanRule {
+	return func(plan Plan) error {
+		var found bool
+		for _, n := range plan.Nodes {
+			if n.RelationName == "" || (n.RelationName != table && n.Schema+"."+n.RelationName != table) {
+				continue
+			}
+			found = true
+			if n.NodeType == "Seq Scan" {
+				return fmt.Errorf("sequential scan on table %s", table)
+			}
+		}
+		if !found {
+			return fmt.Errorf("table %s is not scanned by query", table)
+		}
+		return nil
+	}
+}
+
+// UsesIndex requires index scan of any kind by index.
+func UsesIndex(index string) PlanRule {
+	return func(plan Plan) error {
+		for _, n := range plan.Nodes {
+			if n.IndexName == index {
+				return nil
+			}
+		}
+		return fmt.Errorf("index %s is not used", index)
+	}
+}
+
+// MaxCost limits total cost of the query planned with default settings.
+func MaxCost(cost float64) PlanRule {
+	return func(plan Plan) error {
+		if plan.Cost > cost {
+			return fmt.Errorf("total cost %.2f exceeds %.2f", plan.Cost, cost)
+		}
+		return nil
+	}
+}
+
+// ExplainAssert fails the test if query plan violates any of rules.
+// Scans are checked in plan made with sequential scans disabled: on small test tables planner prefers them
+// even when suitable index exists, so only missing index leads to sequential scan.
+// Cost is checked in plan made with default settings.
+func (p *ClientPg) ExplainAssert(query string, args []any, rules ...PlanRule) {
+	p.t.Helper()
+
+	root, err := p.explain(query, args, false)
+	if err != nil {
+		p.t.Errorf("ExplainAssert: %s", err)
+		return
+	}
+	defaultRoot, err := p.explain(query, args, true)
+	if err != nil {
+		p.t.Errorf("ExplainAssert: %s", err)
+		return
+	}
+
+	plan := Plan{Nodes: flattenPlan(root, nil), Cost: defaultRoot.TotalCost}
+	var violations []string
+	for _, rule := range rules {
+		if err = rule(plan); err != nil {
+			violations = append(violations, err.Error())
+		}
+	}
+	if len(violations) == 0 {
+		return
+	}
+
+	raw, _ := json.MarshalIndent(root, "", "  ")
+	p.t.Errorf("ExplainAssert: %s\nquery: %s\nplan without sequential scans: %s",
+		strings.Join(violations, "; "), query, raw)
+}
+
+func (p *ClientPg) explain(query string, args []any, seqScan bool) (PlanNode, error) {
+	tx, err := p.conn.Begin()
+	if err != nil {
+		return PlanNode{}, fmt.Errorf("begin error: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if !seqScan {
+		_, err = tx.Exec("SET LOCAL enable_seqscan = off")
+		if err != nil {
+			return PlanNode{}, fmt.Errorf("disable seqscan error: %w", err)
+		}
+	}
+
+	var raw []byte
+	err = tx.QueryRow("EXPLAIN (VERBOSE, FORMAT JSON) "+query, args...).Scan(&raw)
+	if err != nil {
+		return PlanNode{}, fmt.Errorf("explain error: %w", err)
+	}
+
+	var plans []struct {
+		Plan PlanNode `json:"Plan"`
+	}
+	err = json.Unmarshal(raw, &plans)
+	if err != nil {
+		return PlanNode{}, fmt.Errorf("explain parse error: %w", err)
+	}
+	if len(plans) == 0 {
+		return PlanNode{}, errors.New("empty plan")
+	}
+	return plans[0].Plan, nil
+}
+
+func flattenPlan(node PlanNode, nodes []PlanNode) []PlanNode {
+	nodes = append(nodes, node)
+	for _, child := range node.Plans {
+		nodes = flattenPlan(child, nodes)
+	}
+	return nodes
+}
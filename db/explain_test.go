@@ -0,0 +1,37 @@
+package db
+
+import "testing"
+
+func TestPlanRules(t *testing.T) {
+	plan := Plan{
+		Nodes: []PlanNode{
+			{NodeType: "Nested Loop", TotalCost: 1e10},
+			{NodeType: "Seq Scan", RelationName: "customers", Schema: "public"},
+			{NodeType: "Index Scan", RelationName: "orders", Schema: "billing", IndexName: "orders_customer_id_idx"},
+		},
+		Cost: 42,
+	}
+
+	tests := []struct {
+		name string
+		rule PlanRule
+		ok   bool
+	}{
+		{"index scan", NoSeqScan("orders"), true},
+		{"schema qualified index scan", NoSeqScan("billing.orders"), true},
+		{"other schema", NoSeqScan("public.orders"), false},
+		{"seq scan", NoSeqScan("customers"), false},
+		{"schema qualified seq scan", NoSeqScan("public.customers"), false},
+		{"missing table", NoSeqScan("payments"), false},
+		{"used index", UsesIndex("orders_customer_id_idx"), true},
+		{"unused index", UsesIndex("orders_status_idx"), false},
+		// forced plan cost is ignored
+		{"cost below", MaxCost(100), true},
+		{"cost above", MaxCost(10), false},
+	}
+	for _, tt := range tests {
+		if err := tt.rule(plan); (err == nil) != tt.ok {
+			t.Errorf("%s: expected ok %t, actual error %v", tt.name, tt.ok, err)
+		}
+	}
+}
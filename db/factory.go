@@ -0,0 +1,270 @@
+package db
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// maxFactoryDepth limits parent rows chain, e.g. for NOT NULL self references.
+const maxFactoryDepth = 10
+
+// factorySeq makes generated values unique across all inserts.
+var factorySeq atomic.Int64
+
+type factoryColumn struct {
+	name     string
+	typeName string // formatted type, e.g. character varying(10)
+	category string // pg_type.typcategory
+	baseType string // pg_type.typname
+	typmod   int
+	notNull  bool
+	auto     bool // has default, identity or is generated
+	enum     string
+}
+
+type factoryForeignKey struct {
+	columns    []string
+	refTable   string
+	refColumns []string
+}
+
+// Insert inserts row to table generating values for all NOT NULL columns without defaults.
+// Overrides set column values explicitly. Rows referenced by NOT NULL foreign keys are created
+// the same way, unless referencing columns are overridden. Returns inserted row.
+func (p *ClientPg) Insert(table string, overrides map[string]any) map[string]any {
+	p.t.Helper()
+
+	row, err := p.insert(table, overrides, 0)
+	if err != nil {
+		p.t.Errorf("Insert %s: %s", table, err)
+		return nil
+	}
+	return row
+}
+
+func (p *ClientPg) insert(table string, overrides map[string]any, depth int) (map[string]any, error) {
+	if depth > maxFactoryDepth {
+		return nil, fmt.Errorf("parent rows depth exceeds %d, provide foreign key overrides", maxFactoryDepth)
+	}
+
+	columns, err := p.factoryColumns(table)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := p.factoryForeignKeys(table)
+	if err != nil {
+		return nil, err
+	}
+
+	// parent rows for NOT NULL foreign keys, values are passed as text like generated ones
+	parentValues := make(map[string]string)
+	for _, key := range keys {
+		if !parentRequired(key, columns, overrides) {
+			continue
+		}
+		parent, err := p.insert(key.refTable, nil, depth+1)
+		if err != nil {
+			return nil, fmt.Errorf("parent %s: %w", key.refTable, err)
+		}
+		for i, column := range key.columns {
+			parentValues[column] = textValue(parent[key.refColumns[i]])
+		}
+	}
+
+	var names, placeholders []string
+	var args []any
+	for _, column := range columns {
+		placeholder := "$" + strconv.Itoa(len(args)+1)
+
+		value, ok := overrides[column.name]
+		if !ok {
+			text, ok := parentValues[column.name]
+			if !ok {
+				if !column.notNull || column.auto {
+					continue
+				}
+				text, err = generateValue(column)
+				if err != nil {
+					return nil, err
+				}
+			}
+			value = text
+			placeholder += "::text::" + column.typeName
+		}
+
+		names = append(names, quoteIdentifier(column.name))
+		placeholders = append(placeholders, placeholder)
+		args = append(args, value)
+	}
+
+	query := "INSERT INTO " + quoteQualified(table) + " DEFAULT VALUES RETURNING *"
+	if len(names) > 0 {
+		query = "INSERT INTO " + quoteQualified(table) + " (" + strings.Join(names, ", ") +
+			") VALUES (" + strings.Join(placeholders, ", ") + ") RETURNING *"
+	}
+
+	rows, err := p.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("insert error: %w", err)
+	}
+	defer rows.Close()
+
+	result, err := scanRows(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(result) != 1 {
+		return nil, fmt.Errorf("insert returned %d rows", len(result))
+	}
+	return result[0], nil
+}
+
+// parentRequired reports whether foreign key has NOT NULL columns with no provided values.
+func parentRequired(key factoryForeignKey, columns []factoryColumn, values map[string]any) bool {
+	for _, name := range key.columns {
+		if _, ok := values[name]; ok {
+			return false
+		}
+	}
+	for _, name := range key.columns {
+		for _, column := range columns {
+			if column.name == name && column.notNull && !column.auto {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *ClientPg) factoryColumns(table string) ([]factoryColumn, error) {
+	// attgenerated appeared in postgres 12, read it through jsonb to support older versions
+	rows, err := p.conn.Query(`SELECT a.attname, format_type(a.atttypid, a.atttypmod), t.typcategory, t.typname,
+			a.atttypmod, a.attnotnull,
+			a.atthasdef OR a.attidentity <> '' OR COALESCE(to_jsonb(a)->>'attgenerated', '') <> '',
+			COALESCE((SELECT e.enumlabel FROM pg_enum e WHERE e.enumtypid = t.oid ORDER BY e.enumsortorder LIMIT 1), '')
+		FROM pg_attribute a
+		JOIN pg_type t ON t.oid = a.atttypid
+		WHERE a.attrelid = $1::regclass AND a.attnum > 0 AND NOT a.attisdropped
+		ORDER BY a.attnum`, quoteQualified(table))
+	if err != nil {
+		return nil, fmt.Errorf("columns query error: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []factoryColumn
+	for rows.Next() {
+		var c factoryColumn
+		err = rows.Scan(&c.name, &c.typeName, &c.category, &c.baseType, &c.typmod, &c.notNull, &c.auto, &c.enum)
+		if err != nil {
+			return nil, fmt.Errorf("columns scan error: %w", err)
+		}
+		columns = append(columns, c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("columns query error: %w", err)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("table %s not found", table)
+	}
+	return columns, nil
+}
+
+func (p *ClientPg) factoryForeignKeys(table string) ([]factoryForeignKey, error) {
+	rows, err := p.conn.Query(`SELECT c.confrelid::regclass::text,
+			(SELECT string_agg(a.attname, ',' ORDER BY k.n) FROM unnest(c.conkey) WITH ORDINALITY k(attnum, n)
+				JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum),
+			(SELECT string_agg(a.attname, ',' ORDER BY k.n) FROM unnest(c.confkey) WITH ORDINALITY k(attnum, n)
+				JOIN pg_attribute a ON a.attrelid = c.confrelid AND a.attnum = k.attnum)
+		FROM pg_constraint c
+		WHERE c.conrelid = $1::regclass AND c.contype = 'f'
+		ORDER BY c.conname`, quoteQualified(table))
+	if err != nil {
+		return nil, fmt.Errorf("foreign keys query error: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []factoryForeignKey
+	for rows.Next() {
+		var key factoryForeignKey
+		var columns, refColumns string
+		err = rows.Scan(&key.refTable, &columns, &refColumns)
+		if err != nil {
+			return nil, fmt.Errorf("foreign keys scan error: %w", err)
+		}
+		key.refTable = strings.ReplaceAll(key.refTable, `"`, "")
+		key.columns = strings.Split(columns, ",")
+		key.refColumns = strings.Split(refColumns, ",")
+		keys = append(keys, key)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("foreign keys query error: %w", err)
+	}
+	return keys, nil
+}
+
+// generateValue returns unique text representation of column value, casted to column type in query.
+func generateValue(c factoryColumn) (string, error) {
+	n := factorySeq.Add(1)
+
+	switch {
+	case c.enum != "":
+		return c.enum, nil
+	case c.baseType == "uuid":
+		return randomUUID()
+	case c.baseType == "json" || c.baseType == "jsonb":
+		return "{}", nil
+	case c.baseType == "bytea":
+		return "", nil
+	}
+
+	switch c.category {
+	case "N": // numeric
+		return strconv.FormatInt(n, 10), nil
+	case "S": // string
+		s := c.name + "_" + strconv.FormatInt(n, 10)
+		// typmod of char types is length + 4
+		if limit := c.typmod - 4; c.typmod > 0 && len(s) > limit {
+			s = s[len(s)-limit:]
+		}
+		return s, nil
+	case "B": // boolean
+		return "false", nil
+	case "D": // date and time
+		return time.Now().UTC().Format(time.RFC3339), nil
+	case "T": // interval
+		return "1 hour", nil
+	case "I": // network address
+		return "127.0.0.1", nil
+	case "A": // array
+		return "{}", nil
+	default:
+		return "", fmt.Errorf("can't generate value for column %s of type %s, provide override", c.name, c.typeName)
+	}
+}
+
+// textValue formats scanned value as postgres text input.
+func textValue(v any) string {
+	switch val := v.(type) {
+	case []byte:
+		return string(val)
+	case time.Time:
+		return val.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+func randomUUID() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", fmt.Errorf("random uuid error: %w", err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
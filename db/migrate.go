@@ -0,0 +1,232 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+// MigrationFormat selects migration files layout and the versions table of the tool owning it.
+type MigrationFormat int
+
+const (
+	// SQLMigrate is rubenv/sql-migrate layout: '-- +migrate Up/Down' sections, gorp_migrations table.
+	SQLMigrate MigrationFormat = iota
+	// GolangMigrate is golang-migrate layout: NNN_name.up.sql/NNN_name.down.sql, schema_migrations table.
+	GolangMigrate
+	// Goose is pressly/goose layout: '-- +goose Up/Down' sections, goose_db_version table.
+	Goose
+)
+
+type migrateConfig struct {
+	format MigrationFormat
+	table  string
+}
+
+type MigrateOption func(*migrateConfig)
+
+// WithFormat sets migration files format, SQLMigrate by default.
+func WithFormat(format MigrationFormat) MigrateOption {
+	return func(c *migrateConfig) {
+		c.format = format
+	}
+}
+
+// WithTable overrides versions table name, if application migrator uses custom one.
+// The table is excluded from Reset, TrackChanges and AssertSchema like default versions tables.
+func WithTable(table string) MigrateOption {
+	return func(c *migrateConfig) {
+		c.table = table
+	}
+}
+
+// migrator applies migrations of a single format.
+type migrator interface {
+	// applied returns applied versions in ascending order.
+	applied() ([]int64, error)
+	// up applies pending migrations up to version inclusive, all of them if version is 0.
+	up(version int64) (int, error)
+	// down rolls back n last applied migrations, all of them if n is 0.
+	down(n int) (int, error)
+}
+
+func (p *ClientPg) migrator(fsys fs.FS, opts []MigrateOption) (migrator, error) {
+	conf := migrateConfig{}
+	for _, o := range opts {
+		o(&conf)
+	}
+	if conf.table != "" {
+		migrationTables.Set(conf.table, struct{}{})
+	}
+
+	switch conf.format {
+	case SQLMigrate:
+		set := migrate.MigrationSet{TableName: conf.table}
+		return &sqlMigrator{conn: p.conn, set: set, source: fsSource(fsys)}, nil
+	case GolangMigrate:
+		files, err := golangMigrateFiles(fsys)
+		if err != nil {
+			return nil, err
+		}
+		if conf.table == "" {
+			conf.table = golangMigrateTable
+		}
+		return &fileMigrator{conn: p.conn, files: files, versions: &golangMigrateVersions{conn: p.conn, table: conf.table}}, nil
+	case Goose:
+		files, err := gooseFiles(fsys)
+		if err != nil {
+			return nil, err
+		}
+		if conf.table == "" {
+			conf.table = gooseTable
+		}
+		return &fileMigrator{conn: p.conn, files: files, versions: &gooseVersions{conn: p.conn, table: conf.table}}, nil
+	default:
+		return nil, fmt.Errorf("unknown migration format %d", conf.format)
+	}
+}
+
+// Migrate applies all up migrations from migrateDir.
+func (p *ClientPg) Migrate(migrateDir string, opts ...MigrateOption) error {
+	m, err := p.migrator(os.DirFS(migrateDir), opts)
+	if err != nil {
+		return fmt.Errorf("pg migrate error: %w", err)
+	}
+
+	n, err := m.up(0)
+	if err != nil {
+		return fmt.Errorf("pg migrate error: %w", err)
+	}
+
+	p.t.Logf("Applied %d migrations. sourse: %s", n, migrateDir)
+	return nil
+}
+
+// MigrateFS applies all up migrations from fsys root.
+// Use fs.Sub to point embed.FS to migrations directory.
+func (p *ClientPg) MigrateFS(fsys fs.FS, opts ...MigrateOption) error {
+	m, err := p.migrator(fsys, opts)
+	if err != nil {
+		return fmt.Errorf("pg migrate error: %w", err)
+	}
+
+	n, err := m.up(0)
+	if err != nil {
+		return fmt.Errorf("pg migrate error: %w", err)
+	}
+
+	p.t.Logf("Applied %d migrations from fs", n)
+	return nil
+}
+
+// MigrateTo applies up or down migrations from fsys until version is the last applied one.
+// Version 0 rolls back all migrations.
+func (p *ClientPg) MigrateTo(fsys fs.FS, version int64, opts ...MigrateOption) error {
+	m, err := p.migrator(fsys, opts)
+	if err != nil {
+		return fmt.Errorf("pg migrate to version %d error: %w", version, err)
+	}
+
+	versions, err := m.applied()
+	if err != nil {
+		return fmt.Errorf("pg migration versions error: %w", err)
+	}
+
+	var above int
+	var applied bool
+	for _, v := range versions {
+		if v > version {
+			above++
+		}
+		if v == version {
+			applied = true
+		}
+	}
+
+	switch {
+	case above > 0:
+		_, err = m.down(above)
+	case !applied && version > 0:
+		_, err = m.up(version)
+	}
+	if err != nil {
+		return fmt.Errorf("pg migrate to version %d error: %w", version, err)
+	}
+
+	p.t.Logf("Migrated to version %d", version)
+	return nil
+}
+
+// MigrateDown rolls back n last applied migrations from fsys, 0 rolls back all of them.
+func (p *ClientPg) MigrateDown(fsys fs.FS, n int, opts ...MigrateOption) error {
+	m, err := p.migrator(fsys, opts)
+	if err != nil {
+		return fmt.Errorf("pg migrate down error: %w", err)
+	}
+
+	applied, err := m.down(n)
+	if err != nil {
+		return fmt.Errorf("pg migrate down error: %w", err)
+	}
+
+	p.t.Logf("Rolled back %d migrations", applied)
+	return nil
+}
+
+// MigrateRoundTrip applies all migrations from fsys, rolls all of them back and applies again,
+// so broken down migrations fail the check.
+func (p *ClientPg) MigrateRoundTrip(fsys fs.FS, opts ...MigrateOption) error {
+	err := p.MigrateFS(fsys, opts...)
+	if err != nil {
+		return fmt.Errorf("round trip up: %w", err)
+	}
+	err = p.MigrateDown(fsys, 0, opts...)
+	if err != nil {
+		return fmt.Errorf("round trip down: %w", err)
+	}
+	err = p.MigrateFS(fsys, opts...)
+	if err != nil {
+		return fmt.Errorf("round trip up again: %w", err)
+	}
+	return nil
+}
+
+// sqlMigrator delegates to rubenv/sql-migrate.
+type sqlMigrator struct {
+	conn   *sql.DB
+	set    migrate.MigrationSet
+	source migrate.MigrationSource
+}
+
+func (m *sqlMigrator) applied() ([]int64, error) {
+	records, err := m.set.GetMigrationRecords(m.conn, postgresDriver)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]int64, 0, len(records))
+	for _, r := range records {
+		versions = append(versions, (&migrate.Migration{Id: r.Id}).VersionInt())
+	}
+	return versions, nil
+}
+
+func (m *sqlMigrator) up(version int64) (int, error) {
+	if version == 0 {
+		return m.set.Exec(m.conn, postgresDriver, m.source, migrate.Up)
+	}
+	return m.set.ExecVersion(m.conn, postgresDriver, m.source, migrate.Up, version)
+}
+
+func (m *sqlMigrator) down(n int) (int, error) {
+	return m.set.ExecMax(m.conn, postgresDriver, m.source, migrate.Down, n)
+}
+
+func fsSource(fsys fs.FS) migrate.MigrationSource {
+	return migrate.HttpFileSystemMigrationSource{
+		FileSystem: http.FS(fsys),
+	}
+}
@@ -0,0 +1,467 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	golangMigrateTable = "schema_migrations"
+	gooseTable         = "goose_db_version"
+)
+
+var (
+	golangMigrateFileRe = regexp.MustCompile(`^(\d+)_(.*)\.(up|down)\.sql$`)
+	gooseFileRe         = regexp.MustCompile(`^(\d+)_(.*)\.sql$`)
+)
+
+// migrationFile is a single parsed migration of golang-migrate or goose format.
+type migrationFile struct {
+	version int64
+	name    string
+	up      []string // statements, executed one by one
+	down    []string
+	noTx    bool
+}
+
+// versionTable tracks applied versions the same way the owning tool does.
+type versionTable interface {
+	ensure() error
+	applied(files []migrationFile) ([]int64, error)
+	markUp(exec executor, version int64) error
+	markDown(exec executor, version, previous int64) error
+}
+
+// dirtyMarker is implemented by version tables recording migration in progress,
+// so failed migration leaves the table dirty like the owning tool does.
+type dirtyMarker interface {
+	markDirty(version int64) error
+}
+
+type executor interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// fileMigrator applies parsed migration files tracking versions in versionTable.
+type fileMigrator struct {
+	conn     *sql.DB
+	files    []migrationFile
+	versions versionTable
+}
+
+func (m *fileMigrator) applied() ([]int64, error) {
+	err := m.versions.ensure()
+	if err != nil {
+		return nil, err
+	}
+	return m.versions.applied(m.files)
+}
+
+func (m *fileMigrator) up(version int64) (int, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return 0, err
+	}
+	done := make(map[int64]struct{}, len(applied))
+	for _, v := range applied {
+		done[v] = struct{}{}
+	}
+
+	if version > 0 && !m.exists(version) {
+		return 0, fmt.Errorf("unknown migration version %d", version)
+	}
+
+	var n int
+	for _, f := range m.files {
+		if version > 0 && f.version > version {
+			break
+		}
+		if _, ok := done[f.version]; ok {
+			continue
+		}
+		if err = m.markDirty(f.version); err != nil {
+			return n, err
+		}
+		err = m.apply(f.up, f.noTx, func(e executor) error {
+			return m.versions.markUp(e, f.version)
+		})
+		if err != nil {
+			return n, fmt.Errorf("migration %d_%s up: %w", f.version, f.name, err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+func (m *fileMigrator) down(n int) (int, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return 0, err
+	}
+
+	var rolled int
+	for i := len(applied) - 1; i >= 0; i-- {
+		if n > 0 && rolled == n {
+			break
+		}
+		f, ok := m.file(applied[i])
+		if !ok {
+			return rolled, fmt.Errorf("applied migration %d not found in source", applied[i])
+		}
+		var previous int64
+		if i > 0 {
+			previous = applied[i-1]
+		}
+		if err = m.markDirty(previous); err != nil {
+			return rolled, err
+		}
+		err = m.apply(f.down, f.noTx, func(e executor) error {
+			return m.versions.markDown(e, f.version, previous)
+		})
+		if err != nil {
+			return rolled, fmt.Errorf("migration %d_%s down: %w", f.version, f.name, err)
+		}
+		rolled++
+	}
+	return rolled, nil
+}
+
+// markDirty records target version of migration about to run, if version table supports it.
+func (m *fileMigrator) markDirty(version int64) error {
+	if d, ok := m.versions.(dirtyMarker); ok {
+		return d.markDirty(version)
+	}
+	return nil
+}
+
+// apply executes migration statements and version record in one transaction, unless noTx is set.
+// Without transaction every statement runs separately, e.g. for CREATE INDEX CONCURRENTLY.
+func (m *fileMigrator) apply(statements []string, noTx bool, mark func(executor) error) error {
+	if noTx {
+		if err := execStatements(m.conn, statements); err != nil {
+			return err
+		}
+		return mark(m.conn)
+	}
+
+	tx, err := m.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("begin error: %w", err)
+	}
+	if err = execStatements(tx, statements); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err = mark(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func execStatements(exec executor, statements []string) error {
+	for _, statement := range statements {
+		if strings.TrimSpace(statement) == "" {
+			continue
+		}
+		if _, err := exec.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *fileMigrator) exists(version int64) bool {
+	_, ok := m.file(version)
+	return ok
+}
+
+func (m *fileMigrator) file(version int64) (migrationFile, bool) {
+	for _, f := range m.files {
+		if f.version == version {
+			return f, true
+		}
+	}
+	return migrationFile{}, false
+}
+
+// golangMigrateFiles reads NNN_name.up.sql and NNN_name.down.sql pairs.
+func golangMigrateFiles(fsys fs.FS) ([]migrationFile, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("read migrations dir error: %w", err)
+	}
+
+	byVersion := make(map[int64]*migrationFile)
+	for _, e := range entries {
+		match := golangMigrateFileRe.FindStringSubmatch(e.Name())
+		if e.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s version error: %w", e.Name(), err)
+		}
+		body, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, fmt.Errorf("read migration %s error: %w", e.Name(), err)
+		}
+
+		f, ok := byVersion[version]
+		if !ok {
+			f = &migrationFile{version: version, name: match[2]}
+			byVersion[version] = f
+		}
+		if f.name != match[2] || (match[3] == "up" && f.up != nil) || (match[3] == "down" && f.down != nil) {
+			return nil, fmt.Errorf("duplicate migration version %d", version)
+		}
+		if match[3] == "up" {
+			f.up = []string{string(body)}
+		} else {
+			f.down = []string{string(body)}
+		}
+		// golang-migrate runs every file as is, without wrapping transaction
+		f.noTx = true
+	}
+	return sortedFiles(byVersion), nil
+}
+
+// gooseFiles reads NNN_name.sql files with '-- +goose Up' and '-- +goose Down' sections.
+func gooseFiles(fsys fs.FS) ([]migrationFile, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("read migrations dir error: %w", err)
+	}
+
+	byVersion := make(map[int64]*migrationFile)
+	for _, e := range entries {
+		match := gooseFileRe.FindStringSubmatch(e.Name())
+		if e.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s version error: %w", e.Name(), err)
+		}
+		if _, ok := byVersion[version]; ok {
+			return nil, fmt.Errorf("duplicate migration version %d", version)
+		}
+		body, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, fmt.Errorf("read migration %s error: %w", e.Name(), err)
+		}
+
+		f, err := parseGoose(string(body))
+		if err != nil {
+			return nil, fmt.Errorf("parse migration %s error: %w", e.Name(), err)
+		}
+		f.version = version
+		f.name = match[2]
+		byVersion[version] = &f
+	}
+	return sortedFiles(byVersion), nil
+}
+
+// parseGoose splits goose file to up and down statements the way goose does: statement ends
+// with a line ending in semicolon, unless it is wrapped in StatementBegin/StatementEnd.
+func parseGoose(body string) (migrationFile, error) {
+	var f migrationFile
+	var current *[]string
+	var statement strings.Builder
+	var block, found bool
+
+	flush := func() {
+		if current != nil && strings.TrimSpace(statement.String()) != "" {
+			*current = append(*current, statement.String())
+		}
+		statement.Reset()
+	}
+
+	for _, line := range strings.SplitAfter(body, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "-- +goose ") {
+			switch strings.ToUpper(strings.TrimSpace(strings.TrimPrefix(trimmed, "-- +goose "))) {
+			case "UP":
+				flush()
+				current, found = &f.up, true
+			case "DOWN":
+				flush()
+				current = &f.down
+			case "STATEMENTBEGIN":
+				flush()
+				block = true
+			case "STATEMENTEND":
+				flush()
+				block = false
+			case "NO TRANSACTION":
+				f.noTx = true
+			}
+			continue
+		}
+		if current == nil || (!block && strings.HasPrefix(trimmed, "--")) {
+			continue
+		}
+
+		statement.WriteString(line)
+		if !block && strings.HasSuffix(trimmed, ";") {
+			flush()
+		}
+	}
+	flush()
+
+	if !found {
+		return f, errors.New("no '-- +goose Up' annotation found")
+	}
+	return f, nil
+}
+
+func sortedFiles(byVersion map[int64]*migrationFile) []migrationFile {
+	files := make([]migrationFile, 0, len(byVersion))
+	for _, f := range byVersion {
+		files = append(files, *f)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].version < files[j].version
+	})
+	return files
+}
+
+// golangMigrateVersions keeps single row with the last applied version and dirty flag.
+type golangMigrateVersions struct {
+	conn  *sql.DB
+	table string
+}
+
+func (v *golangMigrateVersions) ensure() error {
+	_, err := v.conn.Exec(`CREATE TABLE IF NOT EXISTS ` + quoteIdentifier(v.table) +
+		` (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)`)
+	if err != nil {
+		return fmt.Errorf("create %s error: %w", v.table, err)
+	}
+	return nil
+}
+
+// applied returns all known versions not greater than current, golang-migrate keeps no gaps.
+func (v *golangMigrateVersions) applied(files []migrationFile) ([]int64, error) {
+	var current int64
+	var dirty bool
+	err := v.conn.QueryRow(`SELECT version, dirty FROM `+quoteIdentifier(v.table)+` LIMIT 1`).Scan(&current, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("select %s error: %w", v.table, err)
+	}
+	if dirty {
+		return nil, fmt.Errorf("database is dirty at version %d", current)
+	}
+
+	var versions []int64
+	for _, f := range files {
+		if f.version <= current {
+			versions = append(versions, f.version)
+		}
+	}
+	return versions, nil
+}
+
+// markDirty sets target version dirty before migration runs, rolling back the first migration
+// targets version -1 as golang-migrate does.
+func (v *golangMigrateVersions) markDirty(version int64) error {
+	if version == 0 {
+		version = -1
+	}
+	err := v.set(v.conn, version, true)
+	if err != nil {
+		return fmt.Errorf("mark %s dirty error: %w", v.table, err)
+	}
+	return nil
+}
+
+func (v *golangMigrateVersions) markUp(exec executor, version int64) error {
+	return v.set(exec, version, false)
+}
+
+func (v *golangMigrateVersions) markDown(exec executor, _, previous int64) error {
+	if previous == 0 {
+		_, err := exec.Exec(`TRUNCATE ` + quoteIdentifier(v.table))
+		return err
+	}
+	return v.set(exec, previous, false)
+}
+
+func (v *golangMigrateVersions) set(exec executor, version int64, dirty bool) error {
+	_, err := exec.Exec(`TRUNCATE ` + quoteIdentifier(v.table))
+	if err != nil {
+		return err
+	}
+	_, err = exec.Exec(`INSERT INTO `+quoteIdentifier(v.table)+` (version, dirty) VALUES ($1, $2)`, version, dirty)
+	return err
+}
+
+// gooseVersions keeps a row per applied version, version 0 row marks initialized table.
+type gooseVersions struct {
+	conn  *sql.DB
+	table string
+}
+
+func (v *gooseVersions) ensure() error {
+	var exists bool
+	err := v.conn.QueryRow(`SELECT to_regclass($1) IS NOT NULL`, v.table).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("check %s error: %w", v.table, err)
+	}
+	if exists {
+		return nil
+	}
+
+	_, err = v.conn.Exec(`CREATE TABLE ` + quoteIdentifier(v.table) + ` (
+		id serial NOT NULL PRIMARY KEY,
+		version_id bigint NOT NULL,
+		is_applied boolean NOT NULL,
+		tstamp timestamp NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return fmt.Errorf("create %s error: %w", v.table, err)
+	}
+	_, err = v.conn.Exec(`INSERT INTO `+quoteIdentifier(v.table)+` (version_id, is_applied) VALUES ($1, true)`, 0)
+	if err != nil {
+		return fmt.Errorf("init %s error: %w", v.table, err)
+	}
+	return nil
+}
+
+func (v *gooseVersions) applied(_ []migrationFile) ([]int64, error) {
+	rows, err := v.conn.Query(`SELECT DISTINCT version_id FROM ` + quoteIdentifier(v.table) +
+		` WHERE is_applied AND version_id > 0 ORDER BY version_id`)
+	if err != nil {
+		return nil, fmt.Errorf("select %s error: %w", v.table, err)
+	}
+	defer rows.Close()
+
+	var versions []int64
+	for rows.Next() {
+		var version int64
+		if err = rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("scan %s error: %w", v.table, err)
+		}
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
+
+func (v *gooseVersions) markUp(exec executor, version int64) error {
+	_, err := exec.Exec(`INSERT INTO `+quoteIdentifier(v.table)+` (version_id, is_applied) VALUES ($1, true)`, version)
+	return err
+}
+
+func (v *gooseVersions) markDown(exec executor, version, _ int64) error {
+	_, err := exec.Exec(`DELETE FROM `+quoteIdentifier(v.table)+` WHERE version_id = $1`, version)
+	return err
+}
@@ -0,0 +1,142 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseGoose(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		up   []string
+		down []string
+		noTx bool
+		err  bool
+	}{
+		{
+			name: "statements",
+			body: "-- +goose Up\n-- comment\nCREATE TABLE a (id int);\nCREATE TABLE b (\n  id int\n);\n" +
+				"-- +goose Down\nDROP TABLE b;\nDROP TABLE a;\n",
+			up:   []string{"CREATE TABLE a (id int);\n", "CREATE TABLE b (\n  id int\n);\n"},
+			down: []string{"DROP TABLE b;\n", "DROP TABLE a;\n"},
+		},
+		{
+			name: "statement block",
+			body: "-- +goose Up\n-- +goose StatementBegin\nCREATE FUNCTION f() RETURNS int AS $$\nBEGIN\n  RETURN 1;\nEND;\n$$ LANGUAGE plpgsql;\n" +
+				"-- +goose StatementEnd\nSELECT f();\n-- +goose Down\nDROP FUNCTION f;\n",
+			up: []string{
+				"CREATE FUNCTION f() RETURNS int AS $$\nBEGIN\n  RETURN 1;\nEND;\n$$ LANGUAGE plpgsql;\n",
+				"SELECT f();\n",
+			},
+			down: []string{"DROP FUNCTION f;\n"},
+		},
+		{
+			name: "no transaction",
+			body: "-- +goose NO TRANSACTION\n-- +goose Up\nCREATE INDEX CONCURRENTLY a_idx ON a (id);\n" +
+				"CREATE INDEX CONCURRENTLY b_idx ON b (id);\n-- +goose Down\nDROP INDEX CONCURRENTLY a_idx;\n",
+			up:   []string{"CREATE INDEX CONCURRENTLY a_idx ON a (id);\n", "CREATE INDEX CONCURRENTLY b_idx ON b (id);\n"},
+			down: []string{"DROP INDEX CONCURRENTLY a_idx;\n"},
+			noTx: true,
+		},
+		{
+			name: "lower case annotations, statement without semicolon",
+			body: "-- +goose up\nSELECT 1\n",
+			up:   []string{"SELECT 1\n"},
+		},
+		{
+			name: "no up annotation",
+			body: "CREATE TABLE a (id int);\n",
+			err:  true,
+		},
+	}
+	for _, tt := range tests {
+		f, err := parseGoose(tt.body)
+		if (err != nil) != tt.err {
+			t.Errorf("%s: expected error %t, actual %v", tt.name, tt.err, err)
+			continue
+		}
+		if tt.err {
+			continue
+		}
+		if !reflect.DeepEqual(f.up, tt.up) || !reflect.DeepEqual(f.down, tt.down) || f.noTx != tt.noTx {
+			t.Errorf("%s: parsed up %q down %q noTx %t, expected up %q down %q noTx %t",
+				tt.name, f.up, f.down, f.noTx, tt.up, tt.down, tt.noTx)
+		}
+	}
+}
+
+func TestGooseFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"2_orders.sql":             {Data: []byte("-- +goose Up\nCREATE TABLE orders (id int);\n")},
+		"1_init.sql":               {Data: []byte("-- +goose Up\nCREATE TABLE users (id int);\n")},
+		"README.md":                {Data: []byte("not a migration")},
+		"3_directory.sql/file.txt": {Data: []byte("directory is skipped")},
+	}
+	files, err := gooseFiles(fsys)
+	if err != nil {
+		t.Fatalf("goose files error: %s", err)
+	}
+	expected := []migrationFile{
+		{version: 1, name: "init", up: []string{"CREATE TABLE users (id int);\n"}},
+		{version: 2, name: "orders", up: []string{"CREATE TABLE orders (id int);\n"}},
+	}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("goose files = %+v, expected %+v", files, expected)
+	}
+
+	fsys["01_other.sql"] = &fstest.MapFile{Data: []byte("-- +goose Up\nSELECT 1;\n")}
+	if _, err = gooseFiles(fsys); err == nil {
+		t.Errorf("expected duplicate version error")
+	}
+}
+
+func TestGolangMigrateFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"2_orders.up.sql":   {Data: []byte("CREATE TABLE orders (id int);")},
+		"2_orders.down.sql": {Data: []byte("DROP TABLE orders;")},
+		"1_init.up.sql":     {Data: []byte("CREATE TABLE users (id int);\nCREATE INDEX ON users (id);")},
+		"1_init.down.sql":   {Data: []byte("DROP TABLE users;")},
+		"3_init.sql":        {Data: []byte("not a migration")},
+	}
+	files, err := golangMigrateFiles(fsys)
+	if err != nil {
+		t.Fatalf("golang-migrate files error: %s", err)
+	}
+	expected := []migrationFile{
+		{
+			version: 1, name: "init", noTx: true,
+			up:   []string{"CREATE TABLE users (id int);\nCREATE INDEX ON users (id);"},
+			down: []string{"DROP TABLE users;"},
+		},
+		{
+			version: 2, name: "orders", noTx: true,
+			up:   []string{"CREATE TABLE orders (id int);"},
+			down: []string{"DROP TABLE orders;"},
+		},
+	}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("golang-migrate files = %+v, expected %+v", files, expected)
+	}
+
+	duplicates := map[string]fstest.MapFS{
+		"different names": {
+			"1_init.up.sql":  {Data: []byte("SELECT 1;")},
+			"1_other.up.sql": {Data: []byte("SELECT 2;")},
+		},
+		"repeated up": {
+			"1_init.up.sql":  {Data: []byte("SELECT 1;")},
+			"01_init.up.sql": {Data: []byte("SELECT 2;")},
+		},
+		"repeated down": {
+			"1_init.down.sql":  {Data: []byte("SELECT 1;")},
+			"01_init.down.sql": {Data: []byte("SELECT 2;")},
+		},
+	}
+	for name, fsys := range duplicates {
+		if _, err = golangMigrateFiles(fsys); err == nil {
+			t.Errorf("%s: expected duplicate version error", name)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	listenerMinReconnect = 10 * time.Millisecond
+	listenerMaxReconnect = time.Second
+)
+
+// Notification is a single NOTIFY message.
+type Notification struct {
+	Channel string
+	Payload string
+}
+
+// PayloadMatcher reports whether notification payload is expected.
+type PayloadMatcher func(payload string) bool
+
+// PayloadEquals matches payload equal to s.
+func PayloadEquals(s string) PayloadMatcher {
+	return func(payload string) bool {
+		return payload == s
+	}
+}
+
+// PayloadContains matches payload containing s.
+func PayloadContains(s string) PayloadMatcher {
+	return func(payload string) bool {
+		return strings.Contains(payload, s)
+	}
+}
+
+// Subscription receives notifications of a single channel until the end of the test.
+type Subscription struct {
+	t        *testing.T
+	channel  string
+	listener *pq.Listener
+}
+
+// Listen subscribes to channel, notifications sent after the call are received by Next.
+func (p *ClientPg) Listen(channel string) (*Subscription, error) {
+	if s, ok := p.subscriptions.Get(channel); ok {
+		return s, nil
+	}
+
+	listener := pq.NewListener(p.conf.helperDSN(), listenerMinReconnect, listenerMaxReconnect, nil)
+	err := listener.Listen(channel)
+	if err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("listen %s error: %w", channel, err)
+	}
+
+	s := &Subscription{t: p.t, channel: channel, listener: listener}
+	p.subscriptions.Set(channel, s)
+
+	p.t.Cleanup(func() {
+		p.subscriptions.Delete(channel)
+		err := listener.Close()
+		if err != nil {
+			p.t.Errorf("listener %s close error: %s", channel, err)
+		}
+	})
+	return s, nil
+}
+
+// Notify sends notification to channel.
+func (p *ClientPg) Notify(channel, payload string) error {
+	_, err := p.conn.Exec("SELECT pg_notify($1, $2)", channel, payload)
+	if err != nil {
+		return fmt.Errorf("notify %s error: %w", channel, err)
+	}
+	return nil
+}
+
+// ExpectNotification fails the test if no notification matching payload arrives to channel within timeout.
+// Call Listen before the action sending notification, otherwise it may be missed.
+// Nil matcher matches any payload.
+func (p *ClientPg) ExpectNotification(channel string, matcher PayloadMatcher, timeout time.Duration) {
+	p.t.Helper()
+
+	s, err := p.Listen(channel)
+	if err != nil {
+		p.t.Errorf("ExpectNotification: %s", err)
+		return
+	}
+
+	if timeout == 0 {
+		timeout = time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var received []string
+	for {
+		n, err := s.Next(ctx)
+		if err != nil {
+			p.t.Errorf("ExpectNotification: channel %s: no matching notification within %s, received: %q",
+				channel, timeout, received)
+			return
+		}
+		if matcher == nil || matcher(n.Payload) {
+			return
+		}
+		received = append(received, n.Payload)
+	}
+}
+
+// Next waits for the next notification until ctx is done.
+func (s *Subscription) Next(ctx context.Context) (Notification, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return Notification{}, ctx.Err()
+		case n, ok := <-s.listener.Notify:
+			if !ok {
+				return Notification{}, errors.New("listener closed")
+			}
+			// nil is sent after reconnect, notifications may be lost
+			if n == nil {
+				s.t.Logf("listener %s reconnected", s.channel)
+				continue
+			}
+			return Notification{Channel: n.Channel, Payload: n.Extra}, nil
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/FluorescentTouch/testosteron/sync"
+)
+
+const pgxDriver = "pgx"
+
+// NewClientPgx creates client backed by pgxpool, DB and Sqlx share the same pool,
+// so helpers use pgx semantics for arrays, JSONB and other types.
+func NewClientPgx(ctx context.Context, t *testing.T, conf Config) (*ClientPg, error) {
+	pool, err := newPool(ctx, conf)
+	if err != nil {
+		return nil, fmt.Errorf("pgx new pool error: %w", err)
+	}
+
+	t.Log("successful connection to postgres database")
+	conn := stdlib.OpenDBFromPool(pool)
+	client := &ClientPg{
+		t:     t,
+		conn:  conn,
+		connx: sqlx.NewDb(conn, pgxDriver),
+		pool:  pool,
+		conf:  conf,
+
+		subscriptions: sync.MakeSyncMap[*Subscription](),
+	}
+
+	t.Cleanup(func() {
+		err = client.cleanup()
+		if err != nil {
+			t.Errorf("PostgresClient cleanup error: %s", err)
+		}
+	})
+	return client, nil
+}
+
+// PgxPool returns pgx connection pool, nil if client does not use pgx.
+func (p *ClientPg) PgxPool() *pgxpool.Pool {
+	return p.pool
+}
+
+func newPool(ctx context.Context, conf Config) (*pgxpool.Pool, error) {
+	pool, err := pgxpool.New(ctx, conf.helperURL())
+	if err != nil {
+		return nil, fmt.Errorf("pgxpool new error: %w", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			pool.Close()
+			return nil, ctx.Err()
+		default:
+		}
+		e := pool.Ping(ctx)
+		if e == nil {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	return pool, nil
+}
@@ -7,18 +7,28 @@ import (
 	"testing"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
-	migrate "github.com/rubenv/sql-migrate"
+
+	"github.com/FluorescentTouch/testosteron/sync"
 )
 
 type ClientPg struct {
-	t    *testing.T
-	conn *sql.DB
+	t     *testing.T
+	conn  *sql.DB
+	connx *sqlx.DB      // same connection pool as conn
+	pool  *pgxpool.Pool // set for pgx clients only
+	conf  Config
+
+	subscriptions sync.Map[*Subscription] // channel:Subscription
+	changes       *changeTracking         // set by TrackChanges
 }
 
 func NewClientPg(ctx context.Context, t *testing.T, conf Config) (*ClientPg, error) {
-	client := &ClientPg{}
+	client := &ClientPg{
+		subscriptions: sync.MakeSyncMap[*Subscription](),
+	}
 
 	conn, err := client.newConnection(ctx, conf)
 	if err != nil {
@@ -27,7 +37,9 @@ func NewClientPg(ctx context.Context, t *testing.T, conf Config) (*ClientPg, err
 
 	t.Log("successful connection to postgres database")
 	client.t = t
-	client.conn = conn
+	client.conn = conn.DB
+	client.connx = conn
+	client.conf = conf
 
 	t.Cleanup(func() {
 		err = client.cleanup()
@@ -43,11 +55,14 @@ func (p *ClientPg) cleanup() error {
 	if err != nil {
 		return fmt.Errorf("postgres connect close error: %w", err)
 	}
+	if p.pool != nil {
+		p.pool.Close()
+	}
 	return nil
 }
 
-func (p *ClientPg) newConnection(ctx context.Context, conf Config) (*sql.DB, error) {
-	conn, err := sqlx.Open(postgresDriver, conf.String())
+func (p *ClientPg) newConnection(ctx context.Context, conf Config) (*sqlx.DB, error) {
+	conn, err := sqlx.Open(postgresDriver, conf.helperDSN())
 	if err != nil {
 		return nil, fmt.Errorf("sql open error:; %w", err)
 	}
@@ -65,23 +80,34 @@ func (p *ClientPg) newConnection(ctx context.Context, conf Config) (*sql.DB, err
 		time.Sleep(100 * time.Millisecond)
 	}
 
-	return conn.DB, nil
+	return conn, nil
 }
 
 func (p *ClientPg) DB() *sql.DB {
 	return p.conn
 }
 
-func (p *ClientPg) Migrate(migrateDir string) error {
-	migrationsList := &migrate.FileMigrationSource{
-		Dir: migrateDir,
-	}
+// Sqlx returns sqlx wrapper of the DB connection pool.
+func (p *ClientPg) Sqlx() *sqlx.DB {
+	return p.connx
+}
 
-	n, err := migrate.Exec(p.conn, postgresDriver, migrationsList, migrate.Up)
+// Select scans all rows of query result to dst slice pointer using sqlx rules, fails the test on error.
+func (p *ClientPg) Select(dst any, query string, args ...any) {
+	p.t.Helper()
+
+	err := p.connx.Select(dst, query, args...)
 	if err != nil {
-		return fmt.Errorf("pg migrate error: %w", err)
+		p.t.Errorf("Select error: %s", err)
 	}
+}
 
-	p.t.Log(fmt.Sprintf("Applied %d migrations. sourse: %s", n, migrationsList))
-	return nil
+// Get scans single row of query result to dst pointer using sqlx rules, fails the test on error or no rows.
+func (p *ClientPg) Get(dst any, query string, args ...any) {
+	p.t.Helper()
+
+	err := p.connx.Get(dst, query, args...)
+	if err != nil {
+		p.t.Errorf("Get error: %s", err)
+	}
 }
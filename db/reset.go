@@ -0,0 +1,120 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/FluorescentTouch/testosteron/sync"
+)
+
+// serviceTables are never truncated by Reset or tracked by TrackChanges (like audit tables with changesPrefix),
+// so applied migrations stay applied.
+var serviceTables = []string{
+	"gorp_migrations",
+	golangMigrateTable,
+	gooseTable,
+}
+
+// migrationTables are custom versions tables set with WithTable, excluded like serviceTables.
+// Registry is global, as tests usually reset database migrated by another client, e.g. in TestMain.
+var migrationTables = sync.MakeSyncMap[struct{}]()
+
+// Reset truncates all user tables except migration tables and excludeTables,
+// restarting identity sequences. Tables may be excluded by name or by schema.name.
+// Note: CASCADE also truncates excluded tables referencing truncated ones.
+func (p *ClientPg) Reset(excludeTables ...string) error {
+	tables, err := p.userTables(excludeTables...)
+	if err != nil {
+		return err
+	}
+	if len(tables) == 0 {
+		return nil
+	}
+
+	quoted := make([]string, 0, len(tables))
+	for _, table := range tables {
+		quoted = append(quoted, quoteQualified(table))
+	}
+	_, err = p.conn.Exec("TRUNCATE TABLE " + strings.Join(quoted, ", ") + " RESTART IDENTITY CASCADE")
+	if err != nil {
+		return fmt.Errorf("truncate tables error: %w", err)
+	}
+	return nil
+}
+
+// ResetDatabase truncates tables of conf database like Reset, using own connection.
+func ResetDatabase(conf Config, excludeTables ...string) error {
+	return withConnection(conf, conf.DbName, func(conn *sql.DB) error {
+		return (&ClientPg{conn: conn}).Reset(excludeTables...)
+	})
+}
+
+// userTables lists schema.table names of all user tables except service tables, tables of extensions
+// and excludeTables.
+func (p *ClientPg) userTables(excludeTables ...string) ([]string, error) {
+	return p.listTables(true, excludeTables...)
+}
+
+// listTables lists user tables like userTables, partitions are listed only with partitions set.
+func (p *ClientPg) listTables(partitions bool, excludeTables ...string) ([]string, error) {
+	exclude := make(map[string]struct{}, len(serviceTables)+len(excludeTables))
+	for _, table := range serviceTables {
+		exclude[table] = struct{}{}
+	}
+	for _, table := range excludeTables {
+		exclude[table] = struct{}{}
+	}
+
+	// tables created by extensions (e.g. postgis spatial_ref_sys) hold extension data, not test data
+	rows, err := p.conn.Query(`SELECT n.nspname, c.relname
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('r', 'p') AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+			AND ($1 OR NOT c.relispartition)
+			AND NOT EXISTS (SELECT 1 FROM pg_depend d
+				WHERE d.classid = 'pg_class'::regclass AND d.objid = c.oid AND d.deptype = 'e')`, partitions)
+	if err != nil {
+		return nil, fmt.Errorf("list tables error: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var schema, table string
+		err = rows.Scan(&schema, &table)
+		if err != nil {
+			return nil, fmt.Errorf("list tables scan error: %w", err)
+		}
+		if _, ok := exclude[table]; ok {
+			continue
+		}
+		if _, ok := migrationTables.Get(table); ok {
+			continue
+		}
+		if strings.HasPrefix(table, changesPrefix) {
+			continue
+		}
+		if _, ok := exclude[schema+"."+table]; ok {
+			continue
+		}
+		tables = append(tables, schema+"."+table)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("list tables error: %w", err)
+	}
+	return tables, nil
+}
+
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// quoteQualified quotes table name optionally prefixed with schema.
+func quoteQualified(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = quoteIdentifier(part)
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,195 @@
+package db
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const updateFlag = "update"
+
+// RegisterFlags defines -update flag, unless tests already define it for their own golden files.
+// Must be called after package initialization and before flag.Parse, e.g. from TestMain.
+func RegisterFlags() {
+	if flag.Lookup(updateFlag) == nil {
+		flag.Bool(updateFlag, false, "update golden files")
+	}
+}
+
+// AssertSchema compares database schema (tables, columns, constraints, indexes)
+// with golden file. Run tests with -update flag to regenerate it. The flag is registered by steron.Init,
+// tests without Init call RegisterFlags in TestMain or define -update themselves.
+func (p *ClientPg) AssertSchema(goldenFile string) {
+	p.t.Helper()
+
+	actual, err := p.schema()
+	if err != nil {
+		p.t.Errorf("AssertSchema: %s", err)
+		return
+	}
+
+	if f := flag.Lookup(updateFlag); f != nil && f.Value.String() == "true" {
+		err = os.MkdirAll(filepath.Dir(goldenFile), 0o755)
+		if err == nil {
+			err = os.WriteFile(goldenFile, []byte(actual), 0o644)
+		}
+		if err != nil {
+			p.t.Errorf("AssertSchema: update golden file error: %s", err)
+			return
+		}
+		p.t.Logf("schema golden file %s updated", goldenFile)
+		return
+	}
+
+	expected, err := os.ReadFile(goldenFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		p.t.Errorf("AssertSchema: golden file %s not found, run with -%s to create it", goldenFile, updateFlag)
+		if flag.Lookup(updateFlag) == nil {
+			p.t.Errorf("AssertSchema: -%s flag is not registered, call steron.Init or db.RegisterFlags in TestMain", updateFlag)
+		}
+		return
+	}
+	if err != nil {
+		p.t.Errorf("AssertSchema: read golden file error: %s", err)
+		return
+	}
+
+	if string(expected) != actual {
+		p.t.Errorf("AssertSchema: schema differs from %s (-expected +actual), run with -%s to accept:\n%s",
+			goldenFile, updateFlag, lineDiff(string(expected), actual))
+	}
+}
+
+// schema returns readable description of user tables, sorted to be stable between runs.
+func (p *ClientPg) schema() (string, error) {
+	tables, err := p.userTables()
+	if err != nil {
+		return "", err
+	}
+
+	sort.Strings(tables)
+
+	var b strings.Builder
+	for _, table := range tables {
+		fmt.Fprintf(&b, "TABLE %s\n", table)
+
+		lines, err := p.schemaLines(`SELECT 'COLUMN ' || a.attname || ' ' || format_type(a.atttypid, a.atttypmod)
+				|| CASE WHEN a.attnotnull THEN ' NOT NULL' ELSE '' END
+				|| COALESCE(' DEFAULT ' || pg_get_expr(d.adbin, d.adrelid), '')
+			FROM pg_attribute a
+			LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+			WHERE a.attrelid = $1::regclass AND a.attnum > 0 AND NOT a.attisdropped
+			ORDER BY a.attnum`, quoteQualified(table))
+		if err != nil {
+			return "", fmt.Errorf("table %s columns: %w", table, err)
+		}
+		writeLines(&b, lines)
+
+		lines, err = p.schemaLines(`SELECT 'CONSTRAINT ' || conname || ' ' || pg_get_constraintdef(oid)
+			FROM pg_constraint
+			WHERE conrelid = $1::regclass
+			ORDER BY conname`, quoteQualified(table))
+		if err != nil {
+			return "", fmt.Errorf("table %s constraints: %w", table, err)
+		}
+		writeLines(&b, lines)
+
+		lines, err = p.schemaLines(`SELECT 'INDEX ' || i.relname || ' ' || pg_get_indexdef(i.oid)
+			FROM pg_index x
+			JOIN pg_class i ON i.oid = x.indexrelid
+			WHERE x.indrelid = $1::regclass
+			ORDER BY i.relname`, quoteQualified(table))
+		if err != nil {
+			return "", fmt.Errorf("table %s indexes: %w", table, err)
+		}
+		writeLines(&b, lines)
+
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
+
+func (p *ClientPg) schemaLines(query string, args ...any) ([]string, error) {
+	rows, err := p.conn.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []string
+	for rows.Next() {
+		var line string
+		if err = rows.Scan(&line); err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, rows.Err()
+}
+
+func writeLines(b *strings.Builder, lines []string) {
+	for _, line := range lines {
+		b.WriteString("  ")
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+}
+
+// lineDiff returns changed lines based on longest common subsequence,
+// each change is preceded by its TABLE line for context.
+func lineDiff(expected, actual string) string {
+	e := strings.Split(expected, "\n")
+	a := strings.Split(actual, "\n")
+
+	// lcs[i][j] is common subsequence length of e[i:] and a[j:]
+	lcs := make([][]int, len(e)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(a)+1)
+	}
+	for i := len(e) - 1; i >= 0; i-- {
+		for j := len(a) - 1; j >= 0; j-- {
+			if e[i] == a[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var b strings.Builder
+	var section string
+	var printed bool
+	change := func(sign, line string) {
+		if strings.HasPrefix(line, "TABLE ") {
+			section, printed = line, true
+		} else if !printed && section != "" {
+			fmt.Fprintf(&b, "  %s\n", section)
+			printed = true
+		}
+		fmt.Fprintf(&b, "%s %s\n", sign, line)
+	}
+
+	i, j := 0, 0
+	for i < len(e) || j < len(a) {
+		switch {
+		case i < len(e) && j < len(a) && e[i] == a[j]:
+			if strings.HasPrefix(e[i], "TABLE ") {
+				section, printed = e[i], false
+			}
+			i++
+			j++
+		case j < len(a) && (i == len(e) || lcs[i][j+1] >= lcs[i+1][j]):
+			change("+", a[j])
+			j++
+		default:
+			change("-", e[i])
+			i++
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,141 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const maintenanceDatabase = "postgres"
+
+// Snapshot saves current database state to template database, so it can be restored by Restore.
+// All other connections to the database are terminated, application must reconnect.
+func (p *ClientPg) Snapshot(name string) (err error) {
+	admin, err := p.maintenanceConnection()
+	if err != nil {
+		return fmt.Errorf("snapshot %s: %w", name, err)
+	}
+	defer admin.Close()
+
+	snapshot := quoteIdentifier(p.snapshotName(name))
+	_, err = admin.Exec("DROP DATABASE IF EXISTS " + snapshot)
+	if err != nil {
+		return fmt.Errorf("snapshot %s: drop previous error: %w", name, err)
+	}
+
+	err = p.disconnectAll(admin, p.conf.DbName)
+	defer func() {
+		err = errors.Join(err, allowConnections(admin, p.conf.DbName))
+	}()
+	if err != nil {
+		return fmt.Errorf("snapshot %s: %w", name, err)
+	}
+	_, err = admin.Exec("CREATE DATABASE " + snapshot + " TEMPLATE " + quoteIdentifier(p.conf.DbName))
+	if err != nil {
+		return fmt.Errorf("snapshot %s: create error: %w", name, err)
+	}
+
+	p.t.Logf("database snapshot %s created", name)
+	return nil
+}
+
+// Restore recreates database from snapshot made by Snapshot. Snapshot is kept and may be restored again.
+// All other connections to the database are terminated, application must reconnect.
+func (p *ClientPg) Restore(name string) error {
+	admin, err := p.maintenanceConnection()
+	if err != nil {
+		return fmt.Errorf("restore %s: %w", name, err)
+	}
+	defer admin.Close()
+
+	database := quoteIdentifier(p.conf.DbName)
+	err = p.disconnectAll(admin, p.conf.DbName)
+	if err != nil {
+		return errors.Join(fmt.Errorf("restore %s: %w", name, err), allowConnections(admin, p.conf.DbName))
+	}
+	_, err = admin.Exec("DROP DATABASE " + database)
+	if err != nil {
+		return errors.Join(fmt.Errorf("restore %s: drop error: %w", name, err), allowConnections(admin, p.conf.DbName))
+	}
+	// database created from template allows connections
+	_, err = admin.Exec("CREATE DATABASE " + database + " TEMPLATE " + quoteIdentifier(p.snapshotName(name)))
+	if err != nil {
+		return fmt.Errorf("restore %s: create error: %w", name, err)
+	}
+
+	p.t.Logf("database snapshot %s restored", name)
+	return nil
+}
+
+func (p *ClientPg) snapshotName(name string) string {
+	return p.conf.DbName + "_snapshot_" + name
+}
+
+// disconnectAll closes own idle connections, forbids new connections and terminates all other
+// connections to database, template databases can not be copied or dropped while in use.
+// Application pools reconnecting immediately would break it otherwise, see allowConnections.
+func (p *ClientPg) disconnectAll(admin *sql.DB, database string) error {
+	closeIdle(p.conn)
+	if p.pool != nil {
+		p.pool.Reset()
+	}
+
+	_, err := admin.Exec("ALTER DATABASE " + quoteIdentifier(database) + " ALLOW_CONNECTIONS false")
+	if err != nil {
+		return fmt.Errorf("forbid connections error: %w", err)
+	}
+	_, err = admin.Exec(`SELECT pg_terminate_backend(pid) FROM pg_stat_activity
+		WHERE datname = $1 AND pid <> pg_backend_pid()`, database)
+	if err != nil {
+		return fmt.Errorf("terminate connections error: %w", err)
+	}
+	return nil
+}
+
+func allowConnections(admin *sql.DB, database string) error {
+	_, err := admin.Exec("ALTER DATABASE " + quoteIdentifier(database) + " ALLOW_CONNECTIONS true")
+	if err != nil {
+		return fmt.Errorf("allow connections error: %w", err)
+	}
+	return nil
+}
+
+// closeIdle closes idle connections of pool keeping its settings: connections are taken
+// from pool and released as bad ones.
+func closeIdle(conn *sql.DB) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	idle := conn.Stats().Idle
+	conns := make([]*sql.Conn, 0, idle)
+	for i := 0; i < idle; i++ {
+		c, err := conn.Conn(ctx)
+		if err != nil {
+			break
+		}
+		conns = append(conns, c)
+	}
+	for _, c := range conns {
+		_ = c.Raw(func(any) error {
+			return driver.ErrBadConn
+		})
+		_ = c.Close()
+	}
+}
+
+func (p *ClientPg) maintenanceConnection() (*sql.DB, error) {
+	conf := p.conf
+	conf.DbName = maintenanceDatabase
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	conn, err := p.newConnection(ctx, conf)
+	if err != nil {
+		return nil, fmt.Errorf("maintenance connection error: %w", err)
+	}
+	return conn.DB, nil
+}
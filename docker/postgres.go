@@ -2,10 +2,12 @@ package docker
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"strconv"
+	"strings"
 
-	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/modules/postgres"
 )
 
@@ -14,31 +16,55 @@ const (
 	dbPassword = "db_password"
 	dbName     = "testosterone"
 	dbPort     = "5432/tcp"
+
+	// portAttempts limits container starts when chosen host port is taken meanwhile.
+	portAttempts = 3
+
+	// statementLogDir contains csvlog file of all executed statements, see StatementLog.
+	statementLogDir  = "/tmp"
+	statementLogFile = "statements.csv"
 )
 
+var errStatementLogDisabled = errors.New("statement log is disabled, start postgres with WithStatementLog")
+
 type Postgres struct {
-	ctx       context.Context
-	host      string
-	port      int
-	user      string
-	password  string
-	name      string
-	container testcontainers.Container
+	ctx      context.Context
+	host     string
+	port     int
+	user     string
+	password string
+	name     string
+	backend  postgresBackend
 }
 
-func NewPostgres() (*Postgres, error) {
+// postgresBackend runs postgres server: in docker container or from local binaries.
+type postgresBackend interface {
+	statementLog(ctx context.Context, offset int64) (io.ReadCloser, error)
+	restart(ctx context.Context) error
+	pause(ctx context.Context) error
+	unpause(ctx context.Context) error
+	terminate(ctx context.Context) error
+}
+
+func NewPostgres(opts ...PostgresOption) (*Postgres, error) {
 	ctx := context.Background()
 
-	options := []testcontainers.ContainerCustomizer{
-		postgres.WithUsername(dbUserName),
-		postgres.WithPassword(dbPassword),
-		postgres.WithDatabase(dbName),
+	conf := makePostgresConfig(opts)
+	if conf.local {
+		return newLocalPostgres(ctx, conf)
 	}
-	container, err := postgres.RunContainer(ctx, options...)
+
+	container, err := runPostgresContainer(ctx, conf)
 	if err != nil {
 		return nil, fmt.Errorf("could not start container: %w", err)
 	}
 
+	err = createExtensions(ctx, container, conf)
+	if err != nil {
+		_ = container.Terminate(ctx)
+		return nil, err
+	}
+
 	host, err := container.Host(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("postgres get host error: %w", err)
@@ -58,17 +84,63 @@ func NewPostgres() (*Postgres, error) {
 		return nil, fmt.Errorf("port '%s' parse error: %w", hostPost, err)
 	}
 	ps := &Postgres{
-		ctx:       context.Background(),
-		host:      host,
-		port:      port,
-		user:      dbUserName,
-		password:  dbPassword,
-		name:      dbName,
-		container: container,
+		ctx:      context.Background(),
+		host:     host,
+		port:     port,
+		user:     conf.user,
+		password: conf.password,
+		name:     conf.name,
+		backend:  &postgresContainer{container: container, conf: conf},
 	}
 	return ps, nil
 }
 
+// runPostgresContainer starts container with fixed host port, see withHostPort.
+// Free port may be taken by another process before container binds it, then start is retried with a new port.
+func runPostgresContainer(ctx context.Context, conf postgresConfig) (*postgres.PostgresContainer, error) {
+	var err error
+	for attempt := 0; attempt < portAttempts; attempt++ {
+		var hostPort int
+		hostPort, err = freePort()
+		if err != nil {
+			return nil, fmt.Errorf("postgres free port error: %w", err)
+		}
+
+		var container *postgres.PostgresContainer
+		container, err = postgres.RunContainer(ctx, append(conf.customizers(), withHostPort(hostPort))...)
+		if err == nil {
+			return container, nil
+		}
+		if container != nil {
+			_ = container.Terminate(ctx)
+		}
+		if !isPortTaken(err) {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+// isPortTaken reports docker port binding error.
+func isPortTaken(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "port is already allocated") || strings.Contains(msg, "address already in use")
+}
+
+// statementLogSettings enable logging of all statements to statements.csv file in dir.
+// Docker logs are not used: multiline statements are broken by testcontainers log reader.
+func statementLogSettings(dir string) []string {
+	return []string{
+		"logging_collector=on",
+		"log_destination=csvlog",
+		"log_directory=" + dir,
+		"log_filename=statements.log",
+		"log_rotation_age=0",
+		"log_rotation_size=0",
+		"log_statement=all",
+	}
+}
+
 func (p *Postgres) Host() string {
 	return p.host
 }
@@ -89,6 +161,27 @@ func (p *Postgres) Password() string {
 	return p.password
 }
 
+// StatementLog returns csvlog of all statements executed since start, starting at byte offset.
+// Postgres must be started with WithStatementLog.
+func (p *Postgres) StatementLog(ctx context.Context, offset int64) (io.ReadCloser, error) {
+	return p.backend.statementLog(ctx, offset)
+}
+
+// Restart stops and starts postgres, port is kept. Returns when postgres accepts connections.
+func (p *Postgres) Restart(ctx context.Context) error {
+	return p.backend.restart(ctx)
+}
+
+// Pause freezes postgres processes: connections hang instead of failing.
+func (p *Postgres) Pause(ctx context.Context) error {
+	return p.backend.pause(ctx)
+}
+
+// Unpause resumes postgres paused by Pause.
+func (p *Postgres) Unpause(ctx context.Context) error {
+	return p.backend.unpause(ctx)
+}
+
 func (p *Postgres) Cleanup() error {
-	return p.container.Terminate(p.ctx)
+	return p.backend.terminate(p.ctx)
 }
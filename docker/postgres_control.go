@@ -0,0 +1,121 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+	tcexec "github.com/testcontainers/testcontainers-go/exec"
+)
+
+const (
+	readyTimeout      = 30 * time.Second
+	readyPollInterval = 100 * time.Millisecond
+)
+
+// withHostPort binds container port to fixed host port, so it stays the same after Restart.
+func withHostPort(port int) testcontainers.CustomizeRequestOption {
+	return func(req *testcontainers.GenericContainerRequest) {
+		req.ExposedPorts = []string{strconv.Itoa(port) + ":" + dbPort}
+	}
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// postgresContainer runs postgres in docker container.
+type postgresContainer struct {
+	container testcontainers.Container
+	conf      postgresConfig
+}
+
+// statementLog reads log from offset with tail, so growing log is not copied again.
+func (p *postgresContainer) statementLog(ctx context.Context, offset int64) (io.ReadCloser, error) {
+	if !p.conf.statementLog {
+		return nil, errStatementLogDisabled
+	}
+	file := path.Join(statementLogDir, statementLogFile)
+	code, r, err := p.container.Exec(ctx, []string{"tail", "-c", "+" + strconv.FormatInt(offset+1, 10), file},
+		tcexec.Multiplexed())
+	if err != nil {
+		return nil, fmt.Errorf("read %s error: %w", file, err)
+	}
+	if code != 0 {
+		return nil, fmt.Errorf("read %s: tail exit code %d", file, code)
+	}
+	return io.NopCloser(r), nil
+}
+
+// restart stops and starts the container, host port is kept by withHostPort.
+func (p *postgresContainer) restart(ctx context.Context) error {
+	err := p.container.Stop(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("postgres stop error: %w", err)
+	}
+	err = p.container.Start(ctx)
+	if err != nil {
+		return fmt.Errorf("postgres start error: %w", err)
+	}
+	return waitReady(ctx, p.container, p.conf)
+}
+
+func (p *postgresContainer) pause(ctx context.Context) error {
+	return p.withDockerClient(ctx, func(c *testcontainers.DockerClient, id string) error {
+		return c.ContainerPause(ctx, id)
+	})
+}
+
+func (p *postgresContainer) unpause(ctx context.Context) error {
+	return p.withDockerClient(ctx, func(c *testcontainers.DockerClient, id string) error {
+		return c.ContainerUnpause(ctx, id)
+	})
+}
+
+func (p *postgresContainer) terminate(ctx context.Context) error {
+	return p.container.Terminate(ctx)
+}
+
+func (p *postgresContainer) withDockerClient(ctx context.Context, f func(c *testcontainers.DockerClient, id string) error) error {
+	c, err := testcontainers.NewDockerClientWithOpts(ctx)
+	if err != nil {
+		return fmt.Errorf("docker client error: %w", err)
+	}
+	defer c.Close()
+
+	return f(c, p.container.GetContainerID())
+}
+
+// waitReady waits until postgres accepts TCP connections, during init only unix socket is available.
+func waitReady(ctx context.Context, container testcontainers.Container, conf postgresConfig) error {
+	ctx, cancel := context.WithTimeout(ctx, readyTimeout)
+	defer cancel()
+
+	cmd := []string{"pg_isready", "-h", "127.0.0.1", "-U", conf.user, "-d", conf.name}
+	for {
+		code, _, err := container.Exec(ctx, cmd)
+		if err == nil && code == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if err == nil {
+				err = fmt.Errorf("pg_isready exit code %d", code)
+			}
+			return fmt.Errorf("postgres ready wait error: %w", err)
+		case <-time.After(readyPollInterval):
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const localHost = "127.0.0.1"
+
+var (
+	errLocalPause = errors.New("pause is not supported by local postgres")
+	errLocalRoot  = errors.New("local postgres can't run as root: initdb refuses it, run tests as regular user or use docker")
+)
+
+// postgresLocal runs postgres from local binaries, data directory is removed on terminate.
+type postgresLocal struct {
+	binDir string
+	dir    string
+	conf   postgresConfig
+	port   int
+}
+
+func newLocalPostgres(ctx context.Context, conf postgresConfig) (*Postgres, error) {
+	if os.Geteuid() == 0 {
+		return nil, errLocalRoot
+	}
+	binDir, err := localBinDir(conf.binDir)
+	if err != nil {
+		return nil, err
+	}
+	port, err := freePort()
+	if err != nil {
+		return nil, fmt.Errorf("postgres free port error: %w", err)
+	}
+	dir, err := os.MkdirTemp("", "testosteron-postgres-")
+	if err != nil {
+		return nil, fmt.Errorf("postgres temp dir error: %w", err)
+	}
+
+	p := &postgresLocal{binDir: binDir, dir: dir, conf: conf, port: port}
+	err = p.start(ctx)
+	if err != nil {
+		_ = p.terminate(ctx)
+		return nil, err
+	}
+
+	return &Postgres{
+		ctx:      context.Background(),
+		host:     localHost,
+		port:     port,
+		user:     conf.user,
+		password: conf.password,
+		name:     conf.name,
+		backend:  p,
+	}, nil
+}
+
+// start initializes data directory, starts server, creates database, runs init scripts and extensions.
+func (p *postgresLocal) start(ctx context.Context) error {
+	pwfile := filepath.Join(p.dir, "pwfile")
+	err := os.WriteFile(pwfile, []byte(p.conf.password), 0o600)
+	if err != nil {
+		return fmt.Errorf("postgres password file error: %w", err)
+	}
+
+	err = p.run(ctx, "initdb", "-D", p.dataDir(), "-U", p.conf.user, "--pwfile="+pwfile, "--auth=md5", "-E", "UTF8")
+	if err != nil {
+		return err
+	}
+
+	settings := []string{
+		"port=" + strconv.Itoa(p.port),
+		"listen_addresses=" + localHost,
+		"unix_socket_directories=" + p.dir,
+		"fsync=off",
+	}
+	settings = append(settings, p.conf.serverSettings(filepath.Join(p.dir, "log"))...)
+
+	err = p.appendConfig(settings)
+	if err != nil {
+		return err
+	}
+
+	err = p.run(ctx, "pg_ctl", "-D", p.dataDir(), "-l", filepath.Join(p.dir, "server.log"), "-w", "start")
+	if err != nil {
+		return err
+	}
+
+	err = p.psql(ctx, "postgres", "-c", fmt.Sprintf(`CREATE DATABASE "%s"`, p.conf.name))
+	if err != nil {
+		return err
+	}
+	for _, script := range p.conf.initScripts {
+		if filepath.Ext(script) != ".sql" {
+			return fmt.Errorf("init script %s: only .sql scripts are supported by local postgres", script)
+		}
+		err = p.psql(ctx, p.conf.name, "-f", script)
+		if err != nil {
+			return err
+		}
+	}
+	for _, extension := range p.conf.extensions {
+		err = p.psql(ctx, p.conf.name, "-c", fmt.Sprintf(`CREATE EXTENSION IF NOT EXISTS "%s"`, extension))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendConfig writes settings to postgresql.conf, so they survive restart.
+func (p *postgresLocal) appendConfig(settings []string) error {
+	var b strings.Builder
+	for _, setting := range settings {
+		name, value, _ := strings.Cut(setting, "=")
+		fmt.Fprintf(&b, "%s = '%s'\n", name, strings.ReplaceAll(value, "'", "''"))
+	}
+
+	f, err := os.OpenFile(filepath.Join(p.dataDir(), "postgresql.conf"), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("postgres config open error: %w", err)
+	}
+	defer f.Close()
+
+	_, err = f.WriteString(b.String())
+	if err != nil {
+		return fmt.Errorf("postgres config write error: %w", err)
+	}
+	return nil
+}
+
+func (p *postgresLocal) statementLog(_ context.Context, offset int64) (io.ReadCloser, error) {
+	if !p.conf.statementLog {
+		return nil, errStatementLogDisabled
+	}
+	f, err := os.Open(filepath.Join(p.dir, "log", statementLogFile))
+	if err != nil {
+		return nil, err
+	}
+	_, err = f.Seek(offset, io.SeekStart)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+func (p *postgresLocal) restart(ctx context.Context) error {
+	return p.run(ctx, "pg_ctl", "-D", p.dataDir(), "-l", filepath.Join(p.dir, "server.log"), "-m", "fast", "-w", "restart")
+}
+
+func (p *postgresLocal) pause(_ context.Context) error {
+	return errLocalPause
+}
+
+func (p *postgresLocal) unpause(_ context.Context) error {
+	return errLocalPause
+}
+
+func (p *postgresLocal) terminate(ctx context.Context) error {
+	var err error
+	if _, statErr := os.Stat(filepath.Join(p.dataDir(), "postmaster.pid")); statErr == nil {
+		err = p.run(ctx, "pg_ctl", "-D", p.dataDir(), "-m", "immediate", "-w", "stop")
+	}
+	return errors.Join(err, os.RemoveAll(p.dir))
+}
+
+func (p *postgresLocal) dataDir() string {
+	return filepath.Join(p.dir, "data")
+}
+
+func (p *postgresLocal) psql(ctx context.Context, database string, args ...string) error {
+	args = append([]string{
+		"-h", localHost, "-p", strconv.Itoa(p.port), "-U", p.conf.user, "-d", database, "-v", "ON_ERROR_STOP=1",
+	}, args...)
+	return p.run(ctx, "psql", args...)
+}
+
+func (p *postgresLocal) run(ctx context.Context, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, filepath.Join(p.binDir, name), args...)
+	cmd.Env = append(os.Environ(), "PGPASSWORD="+p.conf.password)
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf("%s error: %w: %s", name, err, strings.TrimSpace(output.String()))
+	}
+	return nil
+}
+
+// localBinDir returns binDir or finds postgres binaries in PATH or with pg_config.
+func localBinDir(binDir string) (string, error) {
+	if binDir != "" {
+		return binDir, nil
+	}
+	if path, err := exec.LookPath("initdb"); err == nil {
+		return filepath.Dir(path), nil
+	}
+	if path, err := exec.LookPath("pg_config"); err == nil {
+		out, err := exec.Command(path, "--bindir").Output()
+		if err == nil {
+			return strings.TrimSpace(string(out)), nil
+		}
+	}
+	return "", errors.New("postgres binaries not found: add initdb to PATH or set bin dir with WithLocalBinaries")
+}
@@ -0,0 +1,165 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+)
+
+type postgresConfig struct {
+	image       string
+	user        string
+	password    string
+	name        string
+	settings    []string
+	initScripts []string
+	extensions  []string
+
+	statementLog bool
+
+	local  bool
+	binDir string
+}
+
+type PostgresOption func(*postgresConfig)
+
+// WithImage sets postgres image, e.g. "postgis/postgis:16-3.4" or "pgvector/pgvector:pg16".
+func WithImage(image string) PostgresOption {
+	return func(c *postgresConfig) {
+		c.image = image
+	}
+}
+
+// WithVersion sets official postgres alpine image of version, e.g. "13" or "17".
+func WithVersion(version string) PostgresOption {
+	return func(c *postgresConfig) {
+		c.image = "docker.io/postgres:" + version + "-alpine"
+	}
+}
+
+// WithCredentials sets superuser name and password.
+func WithCredentials(user, password string) PostgresOption {
+	return func(c *postgresConfig) {
+		c.user = user
+		c.password = password
+	}
+}
+
+// WithDatabase sets name of database created on start.
+func WithDatabase(name string) PostgresOption {
+	return func(c *postgresConfig) {
+		c.name = name
+	}
+}
+
+// WithSetting passes server setting as '-c name=value' flag.
+func WithSetting(name, value string) PostgresOption {
+	return func(c *postgresConfig) {
+		c.settings = append(c.settings, name+"="+value)
+	}
+}
+
+// WithInitScripts runs .sql and .sh scripts from host paths on the first start.
+func WithInitScripts(paths ...string) PostgresOption {
+	return func(c *postgresConfig) {
+		c.initScripts = append(c.initScripts, paths...)
+	}
+}
+
+// WithLocalBinaries runs postgres from local binaries (initdb, pg_ctl, psql) in temp directory
+// instead of docker container. Empty binDir means binaries are looked up in PATH and pg_config --bindir.
+// Image options are ignored, init scripts must be .sql files. Tests must not run as root, initdb refuses it.
+func WithLocalBinaries(binDir string) PostgresOption {
+	return func(c *postgresConfig) {
+		c.local = true
+		c.binDir = binDir
+	}
+}
+
+// WithExtensions creates extensions in database after start, image must provide them.
+func WithExtensions(names ...string) PostgresOption {
+	return func(c *postgresConfig) {
+		c.extensions = append(c.extensions, names...)
+	}
+}
+
+// WithStatementLog logs all statements to csvlog file, required by StatementLog and query capture.
+// Logging every statement slows postgres down, so it is disabled by default.
+func WithStatementLog() PostgresOption {
+	return func(c *postgresConfig) {
+		c.statementLog = true
+	}
+}
+
+// serverSettings returns statement log settings if enabled and settings set with WithSetting.
+func (c postgresConfig) serverSettings(logDir string) []string {
+	var settings []string
+	if c.statementLog {
+		settings = append(settings, statementLogSettings(logDir)...)
+	}
+	return append(settings, c.settings...)
+}
+
+func makePostgresConfig(opts []PostgresOption) postgresConfig {
+	conf := postgresConfig{
+		user:     dbUserName,
+		password: dbPassword,
+		name:     dbName,
+	}
+	for _, o := range opts {
+		o(&conf)
+	}
+	return conf
+}
+
+func (c postgresConfig) customizers() []testcontainers.ContainerCustomizer {
+	options := []testcontainers.ContainerCustomizer{
+		postgres.WithUsername(c.user),
+		postgres.WithPassword(c.password),
+		postgres.WithDatabase(c.name),
+	}
+	if c.image != "" {
+		options = append(options, testcontainers.WithImage(c.image))
+	}
+	if len(c.initScripts) > 0 {
+		options = append(options, postgres.WithInitScripts(c.initScripts...))
+	}
+
+	settings := c.serverSettings(statementLogDir)
+	options = append(options, testcontainers.CustomizeRequestOption(func(req *testcontainers.GenericContainerRequest) {
+		for _, setting := range settings {
+			req.Cmd = append(req.Cmd, "-c", setting)
+		}
+	}))
+	return options
+}
+
+// createExtensions runs CREATE EXTENSION with psql inside the container.
+func createExtensions(ctx context.Context, container testcontainers.Container, conf postgresConfig) error {
+	if len(conf.extensions) == 0 {
+		return nil
+	}
+
+	err := waitReady(ctx, container, conf)
+	if err != nil {
+		return err
+	}
+
+	for _, extension := range conf.extensions {
+		cmd := []string{
+			"env", "PGPASSWORD=" + conf.password,
+			"psql", "-h", "127.0.0.1", "-U", conf.user, "-d", conf.name, "-v", "ON_ERROR_STOP=1",
+			"-c", fmt.Sprintf(`CREATE EXTENSION IF NOT EXISTS "%s"`, extension),
+		}
+		code, _, err := container.Exec(ctx, cmd)
+		if err == nil && code != 0 {
+			err = fmt.Errorf("psql exit code %d", code)
+		}
+		if err != nil {
+			return fmt.Errorf("create extension %s error: %w", extension, err)
+		}
+	}
+	return nil
+}
@@ -1,6 +1,9 @@
 package steron
 
-import "github.com/FluorescentTouch/testosteron/sync"
+import (
+	"github.com/FluorescentTouch/testosteron/docker"
+	"github.com/FluorescentTouch/testosteron/sync"
+)
 
 var helper *Helper
 
@@ -14,7 +17,9 @@ func init() {
 			clients: sync.MakeSyncMap[KafkaClient](),
 		},
 		postgres: &PostgresHelper{
-			clients: sync.MakeSyncMap[DbClient](),
+			clients:   sync.MakeSyncMap[DbClient](),
+			databases: sync.MakeSyncMap[*docker.Postgres](),
+			resets:    sync.MakeSyncMap[struct{}](),
 		},
 	}
 	helper = h
@@ -31,12 +31,13 @@ func (h *HTTPHelper) Client(t *testing.T) WebClient {
 	return c
 }
 
-func (h *HTTPHelper) Server(t *testing.T) WebServer {
+// Server returns test server, options are applied when server is created by the first call in test.
+func (h *HTTPHelper) Server(t *testing.T, opts ...server.Option) WebServer {
 	if s, ok := h.servers.Get(t.Name()); ok {
 		return s
 	}
 
-	s := server.NewHTTPServer(t)
+	s := server.NewHTTPServer(t, opts...)
 
 	h.servers.Set(t.Name(), s)
 
@@ -47,7 +48,13 @@ func (h *HTTPHelper) Server(t *testing.T) WebServer {
 	return s
 }
 
-func (h *HTTPHelper) ServerMain(m *testing.M) WebServer {
-	h.mainServer = server.NewHTTPMainServer(m)
+// ServerFromOpenAPI returns new test server for operations of OpenAPI 3 document at specPath,
+// every call creates separate server, e.g. one per dependency.
+func (h *HTTPHelper) ServerFromOpenAPI(t *testing.T, specPath string, opts ...server.Option) OpenAPIServer {
+	return server.NewOpenAPIServer(t, specPath, opts...)
+}
+
+func (h *HTTPHelper) ServerMain(m *testing.M, opts ...server.Option) WebServer {
+	h.mainServer = server.NewHTTPMainServer(m, opts...)
 	return h.mainServer
 }
@@ -2,6 +2,7 @@ package client
 
 import (
 	"bytes"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -27,6 +28,11 @@ func NewHTTPClient(t *testing.T) *HTTPClient {
 
 func (c *HTTPClient) cleanup() {}
 
+// SetTLSConfig configures client for https servers, e.g. with Certificates of TLS test server.
+func (c *HTTPClient) SetTLSConfig(conf *tls.Config) {
+	c.c.Transport = &http.Transport{TLSClientConfig: conf}
+}
+
 func (c *HTTPClient) do(method, url string, bodyIn []byte) (*http.Response, error) {
 	c.t.Helper()
 
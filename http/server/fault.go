@@ -0,0 +1,178 @@
+package server
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// faults configures misbehaviour of stub response, to test client retries and timeouts.
+type faults struct {
+	delay time.Duration
+
+	errorRate   float64
+	errorStatus int
+
+	retryAfter time.Duration
+
+	reset bool
+
+	truncate   bool
+	truncateAt int
+
+	chunkSize     int
+	chunkInterval time.Duration
+}
+
+// WithDelay delays response, delay is interrupted if client cancels request.
+func (s *Stub) WithDelay(d time.Duration) *Stub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults.delay = d
+	return s
+}
+
+// WithErrorRate answers with status instead of configured response for rate (0..1) of requests.
+// Status below 500 (e.g. 0) is replaced with 500.
+func (s *Stub) WithErrorRate(rate float64, status int) *Stub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if status < http.StatusInternalServerError {
+		status = http.StatusInternalServerError
+	}
+	s.faults.errorRate = rate
+	s.faults.errorStatus = status
+	return s
+}
+
+// RespondTooManyRequests answers with 429 and Retry-After header in seconds.
+func (s *Stub) RespondTooManyRequests(retryAfter time.Duration) *Stub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults.retryAfter = retryAfter
+	return s
+}
+
+// ResetConnection closes client connection with TCP reset instead of response.
+func (s *Stub) ResetConnection() *Stub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults.reset = true
+	return s
+}
+
+// TruncateBody sends only first n bytes of body with Content-Length of full body and closes connection.
+func (s *Stub) TruncateBody(n int) *Stub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults.truncate = true
+	s.faults.truncateAt = n
+	return s
+}
+
+// RespondSlowly sends body with chunked encoding, chunkSize bytes per interval.
+func (s *Stub) RespondSlowly(chunkSize int, interval time.Duration) *Stub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults.chunkSize = chunkSize
+	s.faults.chunkInterval = interval
+	return s
+}
+
+// inject writes faulty response, reports whether response is complete.
+// Faults which change only how body is written are applied by write.
+func (f faults) inject(w http.ResponseWriter, r *http.Request) bool {
+	if f.delay > 0 && !sleep(r.Context(), f.delay) {
+		return true
+	}
+	if f.reset {
+		resetConnection(w)
+		return true
+	}
+	if f.errorRate > 0 && rand.Float64() < f.errorRate {
+		http.Error(w, http.StatusText(f.errorStatus), f.errorStatus)
+		return true
+	}
+	if f.retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(f.retryAfter.Seconds()))))
+		w.WriteHeader(http.StatusTooManyRequests)
+		return true
+	}
+	return false
+}
+
+// write writes response status and body, truncated or slowly if configured.
+func (f faults) write(w http.ResponseWriter, r *http.Request, status int, body []byte) {
+	switch {
+	case f.truncate && f.truncateAt < len(body):
+		// server closes connection when less than Content-Length is written
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(status)
+		_, _ = w.Write(body[:f.truncateAt])
+	case f.chunkSize > 0:
+		w.Header().Del("Content-Length")
+		w.WriteHeader(status)
+		flusher, _ := w.(http.Flusher)
+		for len(body) > 0 {
+			n := min(f.chunkSize, len(body))
+			_, err := w.Write(body[:n])
+			if err != nil {
+				return
+			}
+			body = body[n:]
+			if flusher != nil {
+				flusher.Flush()
+			}
+			if len(body) > 0 && !sleep(r.Context(), f.chunkInterval) {
+				return
+			}
+		}
+	default:
+		w.WriteHeader(status)
+		_, _ = w.Write(body)
+	}
+}
+
+// sleep waits for d, returns false if ctx is done earlier.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// resetConnection hijacks connection and closes it with zero linger, so client receives RST.
+func resetConnection(w http.ResponseWriter) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		panic(http.ErrAbortHandler)
+	}
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+
+	raw := conn
+	if c, ok := conn.(interface{ NetConn() net.Conn }); ok {
+		raw = c.NetConn()
+	}
+	if tcp, ok := raw.(*net.TCPConn); ok {
+		_ = tcp.SetLinger(0)
+	}
+	_ = conn.Close()
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestFaults(t *testing.T) {
+	s := NewHTTPServer(t)
+	s.On(http.MethodGet, "/delay").WithDelay(50*time.Millisecond).Respond(http.StatusOK, []byte("late"))
+	s.On(http.MethodGet, "/error").WithErrorRate(1, http.StatusServiceUnavailable)
+	s.On(http.MethodGet, "/error-default").WithErrorRate(1, 0)
+	s.On(http.MethodGet, "/retry").RespondTooManyRequests(1500 * time.Millisecond)
+	s.On(http.MethodGet, "/slow").RespondSlowly(2, 10*time.Millisecond).Respond(http.StatusOK, []byte("abcdef"))
+
+	start := time.Now()
+	resp, body := get(t, s.Addr()+"/delay")
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond || resp.StatusCode != http.StatusOK || body != "late" {
+		t.Errorf("delay: expected 200 late after 50ms, actual %d %q after %s", resp.StatusCode, body, elapsed)
+	}
+
+	if resp, _ = get(t, s.Addr()+"/error"); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("error rate: expected 503, actual %d", resp.StatusCode)
+	}
+	if resp, _ = get(t, s.Addr()+"/error-default"); resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("error rate without status: expected 500, actual %d", resp.StatusCode)
+	}
+
+	resp, _ = get(t, s.Addr()+"/retry")
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") != "2" {
+		t.Errorf("retry after: expected 429 with Retry-After 2, actual %d %q", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+
+	start = time.Now()
+	resp, body = get(t, s.Addr()+"/slow")
+	elapsed := time.Since(start)
+	if len(resp.TransferEncoding) != 1 || resp.TransferEncoding[0] != "chunked" || body != "abcdef" {
+		t.Errorf("slow: expected chunked abcdef, actual %v %q", resp.TransferEncoding, body)
+	}
+	if elapsed < 20*time.Millisecond {
+		t.Errorf("slow: expected at least 20ms, actual %s", elapsed)
+	}
+}
+
+func TestFaultResetConnection(t *testing.T) {
+	s := NewHTTPServer(t)
+	s.On(http.MethodGet, "/reset").ResetConnection()
+
+	resp, err := http.Get(s.Addr() + "/reset")
+	if err == nil {
+		resp.Body.Close()
+		t.Errorf("expected connection error, actual status %d", resp.StatusCode)
+	}
+}
+
+func TestFaultTruncateBody(t *testing.T) {
+	s := NewHTTPServer(t)
+	s.On(http.MethodGet, "/truncate").TruncateBody(5).Respond(http.StatusOK, []byte("hello world"))
+
+	resp, err := http.Get(s.Addr() + "/truncate")
+	if err != nil {
+		t.Fatalf("get error: %s", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err == nil || string(body) != "hello" || resp.ContentLength != int64(len("hello world")) {
+		t.Errorf("expected hello with read error and full Content-Length, actual %q %v %d", body, err, resp.ContentLength)
+	}
+}
+
+// get returns response with read body, response body is closed.
+func get(t *testing.T, url string) (*http.Response, string) {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("get %s error: %s", url, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("get %s read body error: %s", url, err)
+	}
+	return resp, string(body)
+}
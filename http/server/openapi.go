@@ -0,0 +1,364 @@
+package server
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxExampleDepth limits generated example nesting, e.g. for recursive schemas.
+const maxExampleDepth = 8
+
+// OpenAPIServer is HTTPServer with routes for every operation of OpenAPI 3 document.
+// Requests are validated against the document, invalid requests fail the test and get 400.
+// Operations respond with documented example, or with value generated from response schema.
+// Do not initialize manualy, use ServerFromOpenAPI(t, specPath) instead.
+type OpenAPIServer struct {
+	*HTTPServer
+
+	mu         sync.Mutex
+	operations map[string]*openapi3.Operation // key:operation
+	handlers   map[string]http.HandlerFunc    // key:handler
+	statuses   map[string]int                 // key:status
+}
+
+// NewOpenAPIServer loads document from specPath and starts HTTPServer serving its operations.
+// Paths are prefixed with path of the first server in document, if any.
+func NewOpenAPIServer(t *testing.T, specPath string, opts ...Option) *OpenAPIServer {
+	t.Helper()
+
+	loader := openapi3.NewLoader()
+	loader.IsExternalRefsAllowed = true
+	doc, err := loader.LoadFromFile(specPath)
+	if err != nil {
+		t.Fatalf("OpenAPIServer load %s: %s", specPath, err)
+	}
+	err = doc.Validate(loader.Context)
+	if err != nil {
+		t.Fatalf("OpenAPIServer invalid document %s: %s", specPath, err)
+	}
+
+	s := &OpenAPIServer{
+		HTTPServer: NewHTTPServer(t, opts...),
+		operations: make(map[string]*openapi3.Operation),
+		handlers:   make(map[string]http.HandlerFunc),
+		statuses:   make(map[string]int),
+	}
+
+	base := basePath(doc)
+	for path, item := range doc.Paths.Map() {
+		for method, op := range item.Operations() {
+			route := &routers.Route{Spec: doc, Path: path, PathItem: item, Method: method, Operation: op}
+			key := operationKey(method, path, op)
+			s.operations[key] = op
+			s.r.MethodFunc(method, base+path, s.operationHandler(route, key))
+		}
+	}
+	return s
+}
+
+// HandleOperation serves operation with handler instead of example response, requests are still validated.
+// Operation is identified by operationId, or by "METHOD /path" if document has no operationId for it.
+func (s *OpenAPIServer) HandleOperation(operation string, handler http.HandlerFunc) {
+	s.t.Helper()
+
+	if !s.knownOperation(operation) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handlers[operation] = handler
+}
+
+// RespondOperation makes operation respond with documented response of status, e.g. error example.
+func (s *OpenAPIServer) RespondOperation(operation string, status int) {
+	s.t.Helper()
+
+	if !s.knownOperation(operation) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, resp := pickResponse(s.operations[operation], status); resp == nil {
+		s.t.Errorf("OpenAPIServer: operation %s has no response %d", operation, status)
+		return
+	}
+	s.statuses[operation] = status
+}
+
+func (s *OpenAPIServer) knownOperation(operation string) bool {
+	s.t.Helper()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.operations[operation]; !ok {
+		s.t.Errorf("OpenAPIServer: operation %s not found in document", operation)
+		return false
+	}
+	return true
+}
+
+func (s *OpenAPIServer) operationHandler(route *routers.Route, key string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := make(map[string]string)
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			for i, name := range rctx.URLParams.Keys {
+				params[name] = rctx.URLParams.Values[i]
+			}
+		}
+
+		err := openapi3filter.ValidateRequest(r.Context(), &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: params,
+			Route:      route,
+			Options:    &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc},
+		})
+		if err != nil {
+			s.t.Errorf("OpenAPIServer invalid request %s %s: %s", r.Method, r.URL.Path, err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		s.mu.Lock()
+		handler, status := s.handlers[key], s.statuses[key]
+		s.mu.Unlock()
+
+		if handler != nil {
+			handler(w, r)
+			return
+		}
+		writeExample(w, route.Operation, status)
+	}
+}
+
+func operationKey(method, path string, op *openapi3.Operation) string {
+	if op.OperationID != "" {
+		return op.OperationID
+	}
+	return method + " " + path
+}
+
+// basePath returns path of the first server, servers with variables are ignored.
+func basePath(doc *openapi3.T) string {
+	if len(doc.Servers) == 0 || strings.Contains(doc.Servers[0].URL, "{") {
+		return ""
+	}
+	u, err := url.Parse(doc.Servers[0].URL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(u.Path, "/")
+}
+
+// pickResponse returns response of status, or the first successful response if status is 0.
+// Status without own response is served by default response.
+func pickResponse(op *openapi3.Operation, status int) (int, *openapi3.Response) {
+	if op.Responses == nil {
+		return 0, nil
+	}
+	responses := op.Responses.Map()
+
+	if status != 0 {
+		if ref, ok := responses[strconv.Itoa(status)]; ok {
+			return status, ref.Value
+		}
+		if ref, ok := responses[strconv.Itoa(status/100)+"XX"]; ok {
+			return status, ref.Value
+		}
+		if ref, ok := responses["default"]; ok {
+			return status, ref.Value
+		}
+		return 0, nil
+	}
+
+	codes := make([]string, 0, len(responses))
+	for code := range responses {
+		if code != "default" {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		if code[0] == '2' {
+			return responseStatus(code), responses[code].Value
+		}
+	}
+	if ref, ok := responses["default"]; ok {
+		return http.StatusOK, ref.Value
+	}
+	if len(codes) > 0 {
+		return responseStatus(codes[0]), responses[codes[0]].Value
+	}
+	return 0, nil
+}
+
+// responseStatus converts response code to status, ranges like 2XX become 200.
+func responseStatus(code string) int {
+	status, err := strconv.Atoi(strings.ReplaceAll(strings.ToUpper(code), "X", "0"))
+	if err != nil {
+		return http.StatusOK
+	}
+	return status
+}
+
+// writeExample writes documented example of response, JSON content is preferred.
+func writeExample(w http.ResponseWriter, op *openapi3.Operation, status int) {
+	status, resp := pickResponse(op, status)
+	if resp == nil {
+		http.Error(w, "no response in document", http.StatusNotImplemented)
+		return
+	}
+	if len(resp.Content) == 0 {
+		w.WriteHeader(status)
+		return
+	}
+
+	contentType := "application/json"
+	media := resp.Content.Get(contentType)
+	if media == nil {
+		types := make([]string, 0, len(resp.Content))
+		for t := range resp.Content {
+			types = append(types, t)
+		}
+		sort.Strings(types)
+		contentType, media = types[0], resp.Content[types[0]]
+	}
+
+	value := mediaExample(media)
+	var body []byte
+	if text, ok := value.(string); ok && !strings.Contains(contentType, "json") {
+		body = []byte(text)
+	} else {
+		var err error
+		body, err = json.Marshal(value)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+func mediaExample(media *openapi3.MediaType) any {
+	if media.Example != nil {
+		return media.Example
+	}
+	if len(media.Examples) > 0 {
+		names := make([]string, 0, len(media.Examples))
+		for name := range media.Examples {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if ref := media.Examples[names[0]]; ref != nil && ref.Value != nil {
+			return ref.Value.Value
+		}
+	}
+	if media.Schema == nil {
+		return nil
+	}
+	return schemaExample(media.Schema.Value, 0)
+}
+
+// schemaExample generates value valid for common schemas: uses example, default or enum if present,
+// fills all properties of objects and one item of arrays.
+func schemaExample(schema *openapi3.Schema, depth int) any {
+	if schema == nil || depth > maxExampleDepth {
+		return nil
+	}
+	switch {
+	case schema.Example != nil:
+		return schema.Example
+	case schema.Default != nil:
+		return schema.Default
+	case len(schema.Enum) > 0:
+		return schema.Enum[0]
+	case len(schema.OneOf) > 0:
+		return schemaExample(schema.OneOf[0].Value, depth+1)
+	case len(schema.AnyOf) > 0:
+		return schemaExample(schema.AnyOf[0].Value, depth+1)
+	case len(schema.AllOf) > 0:
+		merged := make(map[string]any)
+		for _, ref := range schema.AllOf {
+			if obj, ok := schemaExample(ref.Value, depth+1).(map[string]any); ok {
+				for k, v := range obj {
+					merged[k] = v
+				}
+			}
+		}
+		return merged
+	}
+
+	switch {
+	case schema.Type.Is(openapi3.TypeObject) || (schema.Type == nil && len(schema.Properties) > 0):
+		obj := make(map[string]any, len(schema.Properties))
+		for name, ref := range schema.Properties {
+			obj[name] = schemaExample(ref.Value, depth+1)
+		}
+		return obj
+	case schema.Type.Is(openapi3.TypeArray):
+		if schema.Items == nil {
+			return []any{}
+		}
+		return []any{schemaExample(schema.Items.Value, depth+1)}
+	case schema.Type.Is(openapi3.TypeString):
+		return stringExample(schema)
+	case schema.Type.Is(openapi3.TypeInteger):
+		if schema.Min != nil {
+			return int64(math.Ceil(*schema.Min))
+		}
+		return 0
+	case schema.Type.Is(openapi3.TypeNumber):
+		if schema.Min != nil {
+			return *schema.Min
+		}
+		return 0
+	case schema.Type.Is(openapi3.TypeBoolean):
+		return true
+	default:
+		return nil
+	}
+}
+
+func stringExample(schema *openapi3.Schema) string {
+	switch schema.Format {
+	case "date-time":
+		return time.Now().UTC().Format(time.RFC3339)
+	case "date":
+		return time.Now().UTC().Format(time.DateOnly)
+	case "uuid":
+		return "00000000-0000-4000-8000-000000000000"
+	case "email":
+		return "user@example.com"
+	case "uri", "url":
+		return "https://example.com"
+	}
+
+	s := "string"
+	if n := int(schema.MinLength); len(s) < n {
+		s += strings.Repeat("x", n-len(s))
+	}
+	if schema.MaxLength != nil && uint64(len(s)) > *schema.MaxLength {
+		s = s[:*schema.MaxLength]
+	}
+	return s
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const paymentsSpec = "testdata/payments.yaml"
+
+func TestOpenAPIServer(t *testing.T) {
+	s := NewOpenAPIServer(t, paymentsSpec)
+
+	// documented example
+	resp, body := get(t, s.Addr()+"/v1/payments/7")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("getPayment: expected 200 json, actual %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	assertJSON(t, "getPayment", body, `{"id": 7, "status": "paid", "tags": ["card"]}`)
+
+	// paths are served with server base path only
+	if resp, _ = get(t, s.Addr()+"/payments/7"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("path without base: expected 404, actual %d", resp.StatusCode)
+	}
+
+	// generated from schema
+	resp, body = post(t, s.Addr()+"/v1/payments", `{"amount": 5}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("createPayment: expected 201, actual %d", resp.StatusCode)
+	}
+	assertJSON(t, "createPayment", body, `{"id": 1, "status": "new", "tags": ["string"]}`)
+
+	// operation without operationId, response without content
+	if resp, _ = get(t, s.Addr()+"/v1/health"); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("GET /health: expected 204, actual %d", resp.StatusCode)
+	}
+
+	s.RespondOperation("getPayment", http.StatusNotFound)
+	resp, body = get(t, s.Addr()+"/v1/payments/7")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("getPayment 404: expected 404, actual %d", resp.StatusCode)
+	}
+	assertJSON(t, "getPayment 404", body, `{"error": "not found"}`)
+
+	s.HandleOperation("createPayment", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+	})
+	if resp, _ = post(t, s.Addr()+"/v1/payments", `{"amount": 5}`); resp.StatusCode != http.StatusConflict {
+		t.Errorf("createPayment handler: expected 409, actual %d", resp.StatusCode)
+	}
+	s.HandleOperation("GET /health", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	if resp, _ = get(t, s.Addr()+"/v1/health"); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("GET /health handler: expected 503, actual %d", resp.StatusCode)
+	}
+}
+
+// TestOpenAPIServerInvalidRequest runs itself in subprocess, as invalid request fails the test by design.
+func TestOpenAPIServerInvalidRequest(t *testing.T) {
+	if os.Getenv("OPENAPI_INVALID_REQUEST") == "1" {
+		s := NewOpenAPIServer(t, paymentsSpec)
+		resp, _ := post(t, s.Addr()+"/v1/payments", `{"amount": 0}`)
+		t.Logf("status %d", resp.StatusCode)
+		return
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestOpenAPIServerInvalidRequest$", "-test.v")
+	cmd.Env = append(os.Environ(), "OPENAPI_INVALID_REQUEST=1")
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Errorf("expected test failure on invalid request, output:\n%s", out)
+	}
+	if !strings.Contains(string(out), "OpenAPIServer invalid request POST /v1/payments") ||
+		!strings.Contains(string(out), "status 400") {
+		t.Errorf("expected invalid request error and 400 response, output:\n%s", out)
+	}
+}
+
+// post sends JSON body, returns response with read body.
+func post(t *testing.T, url, body string) (*http.Response, string) {
+	t.Helper()
+
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("post %s error: %s", url, err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("post %s read body error: %s", url, err)
+	}
+	return resp, string(raw)
+}
+
+func assertJSON(t *testing.T, name, actual, expected string) {
+	t.Helper()
+
+	var a, e any
+	if err := json.Unmarshal([]byte(actual), &a); err != nil {
+		t.Errorf("%s: invalid JSON %q: %s", name, actual, err)
+		return
+	}
+	if err := json.Unmarshal([]byte(expected), &e); err != nil {
+		t.Fatalf("%s: invalid expected JSON: %s", name, err)
+	}
+	if !reflect.DeepEqual(a, e) {
+		t.Errorf("%s: expected %s, actual %s", name, expected, actual)
+	}
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// override routes pattern with own chi router, so pattern matching and
+// chi.URLParam work the same way as for handlers registered with HandleFunc.
+type override struct {
+	router *chi.Mux
+}
+
+// overrides replaces handlers registered with HandleFunc for duration of a test.
+type overrides struct {
+	mu        sync.Mutex
+	overrides []*override
+}
+
+func newOverrides() *overrides {
+	return &overrides{}
+}
+
+// Override serves requests matching pattern with handler until the end of test t,
+// then handler registered with HandleFunc is used again. Pattern has chi syntax like in HandleFunc,
+// {param} segments are available to handler with chi.URLParam.
+// The latest override wins, so tests overriding the same pattern must not run in parallel.
+func (o *overrides) Override(t *testing.T, pattern string, handler http.HandlerFunc) {
+	t.Helper()
+
+	ov := &override{router: chi.NewRouter()}
+	ov.router.HandleFunc(pattern, handler)
+
+	o.mu.Lock()
+	o.overrides = append(o.overrides, ov)
+	o.mu.Unlock()
+
+	t.Cleanup(func() {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+
+		for i, v := range o.overrides {
+			if v == ov {
+				o.overrides = append(o.overrides[:i], o.overrides[i+1:]...)
+				break
+			}
+		}
+	})
+}
+
+func (o *overrides) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ov := o.find(r); ov != nil {
+			ov.router.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (o *overrides) find(r *http.Request) *override {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for i := len(o.overrides) - 1; i >= 0; i-- {
+		if o.overrides[i].router.Match(chi.NewRouteContext(), r.Method, r.URL.Path) {
+			return o.overrides[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestOverride(t *testing.T) {
+	s := NewHTTPServer(t)
+	s.HandleFunc("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("default " + chi.URLParam(r, "id")))
+	})
+	s.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	t.Run("override", func(t *testing.T) {
+		s.Override(t, "/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("override " + chi.URLParam(r, "id")))
+		})
+		s.Override(t, "/users/{id}/orders", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("orders " + chi.URLParam(r, "id")))
+		})
+
+		if _, body := get(t, s.Addr()+"/users/7"); body != "override 7" {
+			t.Errorf("expected override 7, actual %q", body)
+		}
+		if _, body := get(t, s.Addr()+"/users/7/orders"); body != "orders 7" {
+			t.Errorf("expected orders 7, actual %q", body)
+		}
+		// not overridden paths are served by default handlers
+		if _, body := get(t, s.Addr()+"/health"); body != "ok" {
+			t.Errorf("expected ok, actual %q", body)
+		}
+	})
+
+	// override is reverted in subtest cleanup
+	if _, body := get(t, s.Addr()+"/users/7"); body != "default 7" {
+		t.Errorf("expected default 7, actual %q", body)
+	}
+}
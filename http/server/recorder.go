@@ -0,0 +1,115 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Request is a copy of incoming request recorded by test server.
+type Request struct {
+	Method string
+	Path   string
+	Query  string
+	Header http.Header
+	Body   []byte
+	Time   time.Time
+}
+
+func (r Request) String() string {
+	s := r.Method + " " + r.Path
+	if r.Query != "" {
+		s += "?" + r.Query
+	}
+	return s
+}
+
+func newRequest(r *http.Request, body []byte) Request {
+	return Request{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  r.URL.RawQuery,
+		Header: r.Header.Clone(),
+		Body:   body,
+		Time:   time.Now(),
+	}
+}
+
+// recorder records all incoming requests of test server.
+type recorder struct {
+	mu       sync.Mutex
+	requests []Request
+}
+
+func newRecorder() *recorder {
+	return &recorder{}
+}
+
+func (rec *recorder) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body []byte
+		if r.Body != nil {
+			body, _ = io.ReadAll(r.Body)
+			_ = r.Body.Close()
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		rec.mu.Lock()
+		rec.requests = append(rec.requests, newRequest(r, body))
+		rec.mu.Unlock()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Requests returns all requests received by server in order.
+func (rec *recorder) Requests() []Request {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	requests := make([]Request, len(rec.requests))
+	copy(requests, rec.requests)
+	return requests
+}
+
+// AssertCalled fails the test if server received other than times requests with method and path.
+func (rec *recorder) AssertCalled(t *testing.T, method, path string, times int) {
+	t.Helper()
+
+	if n := rec.count(method, path); n != times {
+		t.Errorf("AssertCalled: expected %d calls of %s %s, actual %d, received:\n%s",
+			times, method, path, n, rec.received())
+	}
+}
+
+// AssertNotCalled fails the test if server received any request with method and path.
+func (rec *recorder) AssertNotCalled(t *testing.T, method, path string) {
+	t.Helper()
+
+	if n := rec.count(method, path); n != 0 {
+		t.Errorf("AssertNotCalled: %s %s called %d times", method, path, n)
+	}
+}
+
+func (rec *recorder) count(method, path string) int {
+	var n int
+	for _, r := range rec.Requests() {
+		if r.Method == method && r.Path == path {
+			n++
+		}
+	}
+	return n
+}
+
+func (rec *recorder) received() string {
+	var b strings.Builder
+	for _, r := range rec.Requests() {
+		fmt.Fprintf(&b, "  %s\n", r)
+	}
+	return b.String()
+}
@@ -1,8 +1,11 @@
 package server
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/go-chi/chi/v5"
@@ -11,19 +14,56 @@ import (
 // HTTPServer emulates http Server.
 // Do not initialize manualy, use Server(t) instead.
 type HTTPServer struct {
-	s *httptest.Server
-	r *chi.Mux
+	*recorder
+	*stubs
+	*strict
+	*overrides
+
+	s     *httptest.Server
+	r     *chi.Mux
+	certs *Certificates
 
 	t *testing.T
 }
 
-func NewHTTPServer(t *testing.T) *HTTPServer {
+func NewHTTPServer(t *testing.T, opts ...Option) *HTTPServer {
 	t.Helper()
 
+	var conf config
+	for _, opt := range opts {
+		opt(&conf)
+	}
+	var certs *Certificates
+	if conf.tls {
+		var err error
+		certs, err = newCertificates(t.TempDir())
+		if err != nil {
+			t.Fatalf("HTTPServer TLS: %s", err)
+		}
+	}
+
+	rec, st, strict, ov := newRecorder(), newStubs(), newStrict(), newOverrides()
 	r := chi.NewRouter()
-	s := &HTTPServer{s: httptest.NewServer(r), r: r, t: t}
+	// chi skips middlewares until the first route is registered, so the router is wrapped instead
+	h := rec.middleware(st.middleware(ov.middleware(strict.middleware(r))))
+	s := &HTTPServer{
+		recorder:  rec,
+		stubs:     st,
+		strict:    strict,
+		overrides: ov,
+		s:         startServer(h, conf, certs),
+		r:         r,
+		certs:     certs,
+		t:         t,
+	}
 	t.Cleanup(func() {
 		s.Cleanup()
+		for _, unmet := range st.unmet() {
+			t.Errorf("HTTPServer unmet expectation %s", unmet)
+		}
+		for _, unmatched := range strict.report() {
+			t.Errorf("HTTPServer unexpected request %s", unmatched)
+		}
 	})
 	return s
 }
@@ -41,6 +81,11 @@ func (s *HTTPServer) Addr() string {
 	return s.s.URL
 }
 
+// Certificates returns CA and client certificates of TLS server, nil without WithTLS or WithMutualTLS.
+func (s *HTTPServer) Certificates() *Certificates {
+	return s.certs
+}
+
 func (s *HTTPServer) Cleanup() {
 	s.t.Helper()
 
@@ -50,15 +95,49 @@ func (s *HTTPServer) Cleanup() {
 // HTTPMainServer emulates http Server for TestMain.
 // Do not initialize manualy, use ServerMain(m) instead.
 type HTTPMainServer struct {
-	s *httptest.Server
-	r *chi.Mux
+	*recorder
+	*stubs
+	*strict
+	*overrides
+
+	s     *httptest.Server
+	r     *chi.Mux
+	certs *Certificates
 
 	m *testing.M // not required, just for difference between HTTPServer
 }
 
-func NewHTTPMainServer(m *testing.M) *HTTPMainServer {
+// NewHTTPMainServer panics if TLS certificates can't be generated, there is no test to fail.
+func NewHTTPMainServer(m *testing.M, opts ...Option) *HTTPMainServer {
+	var conf config
+	for _, opt := range opts {
+		opt(&conf)
+	}
+	var certs *Certificates
+	if conf.tls {
+		dir, err := os.MkdirTemp("", "testosteron-tls-")
+		if err == nil {
+			certs, err = newCertificates(dir)
+		}
+		if err != nil {
+			panic(fmt.Sprintf("HTTPMainServer TLS: %s", err))
+		}
+	}
+
+	rec, st, strict, ov := newRecorder(), newStubs(), newStrict(), newOverrides()
 	r := chi.NewRouter()
-	s := &HTTPMainServer{s: httptest.NewServer(r), r: r, m: m}
+	// chi skips middlewares until the first route is registered, so the router is wrapped instead
+	h := rec.middleware(st.middleware(ov.middleware(strict.middleware(r))))
+	s := &HTTPMainServer{
+		recorder:  rec,
+		stubs:     st,
+		strict:    strict,
+		overrides: ov,
+		s:         startServer(h, conf, certs),
+		r:         r,
+		certs:     certs,
+		m:         m,
+	}
 	return s
 }
 
@@ -71,6 +150,34 @@ func (s *HTTPMainServer) Addr() string {
 	return s.s.URL
 }
 
+// Certificates returns CA and client certificates of TLS server, nil without WithTLS or WithMutualTLS.
+func (s *HTTPMainServer) Certificates() *Certificates {
+	return s.certs
+}
+
+// Cleanup closes server and reports unmet expectations and unexpected requests to stderr,
+// there is no test to fail.
 func (s *HTTPMainServer) Cleanup() {
 	s.s.Close()
+	if s.certs != nil {
+		_ = os.RemoveAll(filepath.Dir(s.certs.CAFile))
+	}
+	for _, unmet := range s.stubs.unmet() {
+		fmt.Fprintf(os.Stderr, "HTTPMainServer unmet expectation %s\n", unmet)
+	}
+	for _, unmatched := range s.strict.report() {
+		fmt.Fprintf(os.Stderr, "HTTPMainServer unexpected request %s\n", unmatched)
+	}
+}
+
+// startServer starts http server, or https server if TLS is configured.
+func startServer(h http.Handler, conf config, certs *Certificates) *httptest.Server {
+	if !conf.tls {
+		return httptest.NewServer(h)
+	}
+
+	s := httptest.NewUnstartedServer(h)
+	s.TLS = certs.serverTLSConfig(conf.mutual)
+	s.StartTLS()
+	return s
 }
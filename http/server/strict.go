@@ -0,0 +1,98 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// strict records requests which matched neither stub nor route.
+type strict struct {
+	mu        sync.Mutex
+	enabled   bool
+	unmatched []Request
+}
+
+func newStrict() *strict {
+	return &strict{}
+}
+
+// Strict makes requests without matching stub or route fail the test on cleanup.
+// Such requests are answered with 404 or 405 as without strict mode.
+func (s *strict) Strict() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.enabled = true
+}
+
+// middleware records requests answered by chi default 404 and 405 handlers, so responses
+// (e.g. Allow header) stay the same as without strict mode. Routing context is created here
+// to see after serving whether any route matched: matched route always adds its pattern.
+func (s *strict) middleware(router *chi.Mux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rctx := chi.NewRouteContext()
+		rctx.Routes = router
+		r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+
+		router.ServeHTTP(w, r)
+		// RoutePattern can't be used, it is empty for "/" route
+		if len(rctx.RoutePatterns) == 0 {
+			s.record(r)
+		}
+	})
+}
+
+func (s *strict) record(r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.enabled {
+		return
+	}
+
+	var body []byte
+	if r.Body != nil {
+		body, _ = io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	s.unmatched = append(s.unmatched, newRequest(r, body))
+}
+
+// report returns details of unmatched requests, one per entry.
+func (s *strict) report() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := make([]string, 0, len(s.unmatched))
+	for _, r := range s.unmatched {
+		report = append(report, r.details())
+	}
+	return report
+}
+
+// details returns request with headers and body for failure messages.
+func (r Request) details() string {
+	var b strings.Builder
+	b.WriteString(r.String())
+
+	names := make([]string, 0, len(r.Header))
+	for name := range r.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, "\n  %s: %s", name, strings.Join(r.Header[name], ", "))
+	}
+	if len(r.Body) > 0 {
+		fmt.Fprintf(&b, "\n  %s", r.Body)
+	}
+	return b.String()
+}
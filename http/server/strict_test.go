@@ -0,0 +1,73 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestStrictKeepsDefaultResponses(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		r := chi.NewRouter()
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {})
+		r.Get("/orders", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		})
+		s := newStrict()
+		if enabled {
+			s.Strict()
+		}
+		h := s.middleware(r)
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/orders", nil))
+		if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != http.MethodGet {
+			t.Errorf("strict %t: expected 405 with Allow GET, actual %d %q", enabled, w.Code, w.Header().Get("Allow"))
+		}
+
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/missing", nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("strict %t: expected 404, actual %d", enabled, w.Code)
+		}
+
+		// 404 written by route is not an unmatched request
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orders", nil))
+
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("strict %t: expected 200 for root route, actual %d", enabled, w.Code)
+		}
+
+		expected := 0
+		if enabled {
+			expected = 2
+		}
+		if n := len(s.report()); n != expected {
+			t.Errorf("strict %t: expected %d unmatched requests, actual %d", enabled, expected, n)
+		}
+	}
+}
+
+func TestStrictRootRoute(t *testing.T) {
+	s := NewHTTPServer(t)
+	s.Strict()
+	s.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
+
+	resp, err := http.Get(s.Addr() + "/")
+	if err != nil {
+		t.Fatalf("get error: %s", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200, actual %d", resp.StatusCode)
+	}
+	if report := s.strict.report(); len(report) != 0 {
+		t.Errorf("expected no unmatched requests, actual %v", report)
+	}
+}
@@ -0,0 +1,355 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// ScenarioStarted is initial state of every scenario.
+const ScenarioStarted = "Started"
+
+const (
+	// anyTimes marks stub which may be called any number of times, including zero.
+	anyTimes = -1
+	// unsetTimes marks stub without Times, it expects at least one call.
+	unsetTimes = -2
+)
+
+// Stub is a declarative response to matching requests, created by On.
+// Stub without Times expects at least one call.
+type Stub struct {
+	mu sync.Mutex
+
+	method   string
+	path     string
+	matchers []func(r *http.Request, body []byte) bool
+
+	status int
+	header http.Header
+	body   []byte
+
+	times int // unsetTimes means at least once
+	calls int
+	// forbidden counts requests matching stub with Times(0), they are reported as unmet
+	forbidden int
+
+	scenario      string
+	requiredState string
+	newState      string
+
+	faults faults
+}
+
+// WithHeader matches requests with header value.
+func (s *Stub) WithHeader(name, value string) *Stub {
+	return s.match(func(r *http.Request, _ []byte) bool {
+		return r.Header.Get(name) == value
+	})
+}
+
+// WithQuery matches requests with query parameter value.
+func (s *Stub) WithQuery(name, value string) *Stub {
+	return s.match(func(r *http.Request, _ []byte) bool {
+		return r.URL.Query().Get(name) == value
+	})
+}
+
+// WithBody matches requests which body satisfies matcher.
+func (s *Stub) WithBody(matcher func(body []byte) bool) *Stub {
+	return s.match(func(_ *http.Request, body []byte) bool {
+		return matcher(body)
+	})
+}
+
+// WithJSONBody matches requests which JSON body equals expected, keys order and formatting are ignored.
+// Expected may be any value marshalled to JSON, or func(body any) bool for custom matching
+// of decoded body.
+func (s *Stub) WithJSONBody(expected any) *Stub {
+	return s.match(func(_ *http.Request, body []byte) bool {
+		var actual any
+		if err := json.Unmarshal(body, &actual); err != nil {
+			return false
+		}
+		if matcher, ok := expected.(func(body any) bool); ok {
+			return matcher(actual)
+		}
+
+		raw, err := json.Marshal(expected)
+		if err != nil {
+			return false
+		}
+		var want any
+		if err = json.Unmarshal(raw, &want); err != nil {
+			return false
+		}
+		return reflect.DeepEqual(want, actual)
+	})
+}
+
+// Respond sets response status and body.
+func (s *Stub) Respond(status int, body []byte) *Stub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.status = status
+	s.body = body
+	return s
+}
+
+// RespondJSON sets response status and body marshalled to JSON.
+func (s *Stub) RespondJSON(status int, body any) *Stub {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		raw = []byte(fmt.Sprintf(`{"error":%q}`, err.Error()))
+		status = http.StatusInternalServerError
+	}
+	s.RespondHeader("Content-Type", "application/json")
+	return s.Respond(status, raw)
+}
+
+// RespondHeader adds response header.
+func (s *Stub) RespondHeader(name, value string) *Stub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.header.Add(name, value)
+	return s
+}
+
+// Times expects exactly n calls, stub stops matching after n calls.
+// Times(0) expects no calls: stub never matches, matching requests fail the test on cleanup.
+func (s *Stub) Times(n int) *Stub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.times = n
+	return s
+}
+
+// AnyTimes allows any number of calls, including zero.
+func (s *Stub) AnyTimes() *Stub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.times = anyTimes
+	return s
+}
+
+// InScenario ties stub to named scenario, scenario state starts as ScenarioStarted.
+func (s *Stub) InScenario(name string) *Stub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.scenario = name
+	return s
+}
+
+// WhenState matches requests only while scenario is in state.
+func (s *Stub) WhenState(state string) *Stub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requiredState = state
+	return s
+}
+
+// WillSetState moves scenario to state after matched request.
+func (s *Stub) WillSetState(state string) *Stub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.newState = state
+	return s
+}
+
+func (s *Stub) match(matcher func(r *http.Request, body []byte) bool) *Stub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.matchers = append(s.matchers, matcher)
+	return s
+}
+
+// take reports whether request matches stub in scenario states and counts the call.
+func (s *Stub) take(r *http.Request, body []byte, states map[string]string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Method != s.method || !matchPath(s.path, r.URL.Path) {
+		return false
+	}
+	if s.scenario != "" && s.requiredState != "" && scenarioState(states, s.scenario) != s.requiredState {
+		return false
+	}
+	if s.times > 0 && s.calls >= s.times {
+		return false
+	}
+	for _, m := range s.matchers {
+		if !m(r, body) {
+			return false
+		}
+	}
+	if s.times == 0 {
+		s.forbidden++
+		return false
+	}
+	s.calls++
+	if s.scenario != "" && s.newState != "" {
+		states[s.scenario] = s.newState
+	}
+	return true
+}
+
+func (s *Stub) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	status, header, body, faults := s.status, s.header.Clone(), s.body, s.faults
+	s.mu.Unlock()
+
+	if faults.inject(w, r) {
+		return
+	}
+	for name, values := range header {
+		w.Header()[name] = values
+	}
+	faults.write(w, r, status, body)
+}
+
+// unmet returns description of not satisfied expectation, empty if stub is satisfied.
+func (s *Stub) unmet() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case s.times == unsetTimes && s.calls == 0:
+		return fmt.Sprintf("%s %s: expected at least 1 call, actual 0", s.method, s.path)
+	case s.times == 0 && s.forbidden > 0:
+		return fmt.Sprintf("%s %s: expected 0 calls, actual %d", s.method, s.path, s.forbidden)
+	case s.times > 0 && s.calls != s.times:
+		return fmt.Sprintf("%s %s: expected %d calls, actual %d", s.method, s.path, s.times, s.calls)
+	default:
+		return ""
+	}
+}
+
+func scenarioState(states map[string]string, scenario string) string {
+	if state, ok := states[scenario]; ok {
+		return state
+	}
+	return ScenarioStarted
+}
+
+// matchPath matches path to pattern with chi-like {param} segments and trailing *.
+func matchPath(pattern, path string) bool {
+	patternParts := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+
+	for i, part := range patternParts {
+		if part == "*" && i == len(patternParts)-1 {
+			return true
+		}
+		if i >= len(pathParts) {
+			return false
+		}
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			continue
+		}
+		if part != pathParts[i] {
+			return false
+		}
+	}
+	return len(patternParts) == len(pathParts)
+}
+
+// stubs serves requests matching stubs, other requests are passed to router.
+type stubs struct {
+	mu     sync.Mutex
+	stubs  []*Stub
+	states map[string]string // scenario:state
+}
+
+func newStubs() *stubs {
+	return &stubs{states: make(map[string]string)}
+}
+
+// On creates stub for requests with method and path, path may contain {param} segments.
+// Responds 200 with empty body unless configured with Respond.
+func (st *stubs) On(method, path string) *Stub {
+	s := &Stub{
+		method: method,
+		path:   path,
+		status: http.StatusOK,
+		header: make(http.Header),
+		times:  unsetTimes,
+	}
+
+	st.mu.Lock()
+	st.stubs = append(st.stubs, s)
+	st.mu.Unlock()
+
+	return s
+}
+
+func (st *stubs) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body []byte
+		if r.Body != nil {
+			body, _ = io.ReadAll(r.Body)
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		if s := st.take(r, body); s != nil {
+			s.serve(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// take returns first stub matching request, matching and scenario transition are atomic.
+func (st *stubs) take(r *http.Request, body []byte) *Stub {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	for _, s := range st.stubs {
+		if s.take(r, body, st.states) {
+			return s
+		}
+	}
+	return nil
+}
+
+// ScenarioState returns current state of scenario.
+func (st *stubs) ScenarioState(scenario string) string {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	return scenarioState(st.states, scenario)
+}
+
+// SetScenarioState moves scenario to state, e.g. to start test from the middle of workflow.
+func (st *stubs) SetScenarioState(scenario, state string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.states[scenario] = state
+}
+
+// unmet returns descriptions of all not satisfied stubs.
+func (st *stubs) unmet() []string {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var unmet []string
+	for _, s := range st.stubs {
+		if u := s.unmet(); u != "" {
+			unmet = append(unmet, u)
+		}
+	}
+	return unmet
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStubWithoutRoutes(t *testing.T) {
+	s := NewHTTPServer(t)
+	s.On(http.MethodGet, "/ping").Respond(http.StatusCreated, []byte("pong")).Times(1)
+
+	resp, err := http.Get(s.Addr() + "/ping")
+	if err != nil {
+		t.Fatalf("get error: %s", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body error: %s", err)
+	}
+	if resp.StatusCode != http.StatusCreated || string(body) != "pong" {
+		t.Errorf("expected 201 pong, actual %d %s", resp.StatusCode, body)
+	}
+	if n := len(s.Requests()); n != 1 {
+		t.Errorf("expected 1 recorded request, actual %d", n)
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		match   bool
+	}{
+		{"/payments", "/payments", true},
+		{"/payments", "/payments/", true},
+		{"/payments", "/payment", false},
+		{"/payments", "/payments/1", false},
+		{"/payments/{id}", "/payments/1", true},
+		{"/payments/{id}", "/payments", false},
+		{"/payments/{id}/refunds", "/payments/1/refunds", true},
+		{"/payments/{id}/refunds", "/payments/1/captures", false},
+		{"/files/*", "/files/a/b/c", true},
+		{"/files/*", "/other/a", false},
+		{"/", "/", true},
+	}
+	for _, tt := range tests {
+		if got := matchPath(tt.pattern, tt.path); got != tt.match {
+			t.Errorf("matchPath(%q, %q) = %v, expected %v", tt.pattern, tt.path, got, tt.match)
+		}
+	}
+}
+
+func TestStubTimes(t *testing.T) {
+	st := newStubs()
+	st.On(http.MethodGet, "/once")
+	st.On(http.MethodGet, "/never").Times(0)
+	st.On(http.MethodGet, "/any").AnyTimes()
+	st.On(http.MethodGet, "/twice").Times(2)
+
+	if unmet := st.unmet(); len(unmet) != 2 ||
+		unmet[0] != "GET /once: expected at least 1 call, actual 0" ||
+		unmet[1] != "GET /twice: expected 2 calls, actual 0" {
+		t.Errorf("unexpected unmet before calls: %q", unmet)
+	}
+
+	// third call to /twice is not matched by exhausted stub
+	h := st.middleware(http.NotFoundHandler())
+	calls := []struct {
+		path   string
+		status int
+	}{
+		{"/once", http.StatusOK},
+		{"/never", http.StatusNotFound},
+		{"/twice", http.StatusOK},
+		{"/twice", http.StatusOK},
+		{"/twice", http.StatusNotFound},
+	}
+	for _, c := range calls {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, c.path, nil))
+		if w.Code != c.status {
+			t.Errorf("GET %s: expected %d, actual %d", c.path, c.status, w.Code)
+		}
+	}
+
+	if unmet := st.unmet(); len(unmet) != 1 || unmet[0] != "GET /never: expected 0 calls, actual 1" {
+		t.Errorf("unexpected unmet after calls: %q", unmet)
+	}
+}
+
+func TestStubScenario(t *testing.T) {
+	s := NewHTTPServer(t)
+	s.On(http.MethodGet, "/order").InScenario("order").WhenState(ScenarioStarted).WillSetState("paid").
+		Respond(http.StatusOK, []byte("new")).Times(1)
+	s.On(http.MethodGet, "/order").InScenario("order").WhenState("paid").WillSetState("shipped").
+		Respond(http.StatusOK, []byte("paid")).AnyTimes()
+	s.On(http.MethodGet, "/order").InScenario("order").WhenState("shipped").
+		Respond(http.StatusOK, []byte("shipped")).AnyTimes()
+
+	if state := s.ScenarioState("order"); state != ScenarioStarted {
+		t.Errorf("expected initial state %s, actual %s", ScenarioStarted, state)
+	}
+	for _, expected := range []string{"new", "paid", "shipped", "shipped"} {
+		if _, body := get(t, s.Addr()+"/order"); body != expected {
+			t.Errorf("expected %s, actual %s", expected, body)
+		}
+	}
+	if state := s.ScenarioState("order"); state != "shipped" {
+		t.Errorf("expected state shipped, actual %s", state)
+	}
+
+	s.SetScenarioState("order", "paid")
+	if _, body := get(t, s.Addr()+"/order"); body != "paid" {
+		t.Errorf("expected paid after SetScenarioState, actual %s", body)
+	}
+}
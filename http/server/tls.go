@@ -0,0 +1,187 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const certValidity = 24 * time.Hour
+
+type config struct {
+	tls    bool
+	mutual bool
+}
+
+// Option configures test server.
+type Option func(*config)
+
+// WithTLS serves https with certificate signed by generated CA, see Certificates.
+func WithTLS() Option {
+	return func(c *config) {
+		c.tls = true
+	}
+}
+
+// WithMutualTLS serves https and requires client certificate signed by generated CA, see Certificates.
+func WithMutualTLS() Option {
+	return func(c *config) {
+		c.tls = true
+		c.mutual = true
+	}
+}
+
+// Certificates of TLS test server. CA signs both server and client certificates,
+// PEM data is also written to files for applications configured with paths.
+type Certificates struct {
+	CAPEM         []byte
+	ClientCertPEM []byte
+	ClientKeyPEM  []byte
+
+	CAFile         string
+	ClientCertFile string
+	ClientKeyFile  string
+
+	server tls.Certificate
+	pool   *x509.CertPool
+}
+
+// ClientTLSConfig returns config trusting server and presenting client certificate.
+func (c *Certificates) ClientTLSConfig() *tls.Config {
+	cert, _ := tls.X509KeyPair(c.ClientCertPEM, c.ClientKeyPEM)
+	return &tls.Config{
+		RootCAs:      c.pool,
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+}
+
+// serverTLSConfig returns config for test server, mutual requires client certificates.
+func (c *Certificates) serverTLSConfig(mutual bool) *tls.Config {
+	conf := &tls.Config{
+		Certificates: []tls.Certificate{c.server},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if mutual {
+		conf.ClientAuth = tls.RequireAndVerifyClientCert
+		conf.ClientCAs = c.pool
+	}
+	return conf
+}
+
+// newCertificates generates CA, server certificate for localhost and client certificate, writes PEM files to dir.
+func newCertificates(dir string) (*Certificates, error) {
+	caKey, caPEM, ca, err := generateCert("testosteron CA", nil, nil, func(tmpl *x509.Certificate) {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	})
+	if err != nil {
+		return nil, fmt.Errorf("CA certificate error: %w", err)
+	}
+
+	serverKey, serverPEM, _, err := generateCert("testosteron server", ca, caKey, func(tmpl *x509.Certificate) {
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		tmpl.DNSNames = []string{"localhost"}
+		tmpl.IPAddresses = []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("server certificate error: %w", err)
+	}
+
+	clientKey, clientPEM, _, err := generateCert("testosteron client", ca, caKey, func(tmpl *x509.Certificate) {
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("client certificate error: %w", err)
+	}
+
+	serverKeyPEM, err := encodeKey(serverKey)
+	if err != nil {
+		return nil, err
+	}
+	clientKeyPEM, err := encodeKey(clientKey)
+	if err != nil {
+		return nil, err
+	}
+	server, err := tls.X509KeyPair(serverPEM, serverKeyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("server key pair error: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	c := &Certificates{
+		CAPEM:          caPEM,
+		ClientCertPEM:  clientPEM,
+		ClientKeyPEM:   clientKeyPEM,
+		CAFile:         filepath.Join(dir, "ca.pem"),
+		ClientCertFile: filepath.Join(dir, "client.pem"),
+		ClientKeyFile:  filepath.Join(dir, "client-key.pem"),
+		server:         server,
+		pool:           pool,
+	}
+
+	for file, data := range map[string][]byte{c.CAFile: c.CAPEM, c.ClientCertFile: c.ClientCertPEM, c.ClientKeyFile: c.ClientKeyPEM} {
+		err = os.WriteFile(file, data, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("certificate file error: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// generateCert creates certificate signed by parent, self-signed if parent is nil.
+func generateCert(
+	name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, customize func(*x509.Certificate),
+) (*ecdsa.PrivateKey, []byte, *x509.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("generate key error: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("serial number error: %w", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(certValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	customize(tmpl)
+
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("create certificate error: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("parse certificate error: %w", err)
+	}
+	return key, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), cert, nil
+}
+
+func encodeKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("marshal key error: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"bytes"
+	"crypto/tls"
+	"net/http"
+	"os"
+	"testing"
+)
+
+func TestTLS(t *testing.T) {
+	s := NewHTTPServer(t, WithTLS())
+	s.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {})
+	certs := s.Certificates()
+
+	if err := tlsGet(s.Addr()+"/ping", certs.ClientTLSConfig()); err != nil {
+		t.Errorf("expected successful request with client config, actual %s", err)
+	}
+	if err := tlsGet(s.Addr()+"/ping", &tls.Config{MinVersion: tls.VersionTLS12}); err == nil {
+		t.Errorf("expected unknown authority error without CA")
+	}
+
+	ca, err := os.ReadFile(certs.CAFile)
+	if err != nil || !bytes.Equal(ca, certs.CAPEM) {
+		t.Errorf("expected CA file with CAPEM, actual error %v", err)
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	s := NewHTTPServer(t, WithMutualTLS())
+	s.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {})
+	certs := s.Certificates()
+
+	if err := tlsGet(s.Addr()+"/ping", certs.ClientTLSConfig()); err != nil {
+		t.Errorf("expected successful request with client certificate, actual %s", err)
+	}
+
+	withoutCert := certs.ClientTLSConfig()
+	withoutCert.Certificates = nil
+	if err := tlsGet(s.Addr()+"/ping", withoutCert); err == nil {
+		t.Errorf("expected handshake error without client certificate")
+	}
+}
+
+// tlsGet sends request with new transport, so connections are not reused between configs.
+func tlsGet(url string, conf *tls.Config) error {
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: conf}}
+	defer client.CloseIdleConnections()
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
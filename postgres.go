@@ -2,6 +2,7 @@ package steron
 
 import (
 	"context"
+	gosync "sync"
 	"testing"
 
 	"github.com/FluorescentTouch/testosteron/db"
@@ -10,42 +11,83 @@ import (
 )
 
 type PostgresHelper struct {
-	clients  sync.Map[DbClient]
-	database *docker.Postgres
+	clients   sync.Map[DbClient]
+	databases sync.Map[*docker.Postgres] // t.Name:Postgres, if not initialized globally
+	database  *docker.Postgres
+	options   []docker.PostgresOption
+
+	resetOnCleanup bool
+	resetExclude   []string
+	resets         sync.Map[struct{}] // t.Name of tests with reset registered
+
+	pgx bool
 }
 
-func (p *PostgresHelper) Client(t *testing.T) DbClient {
-	if c, ok := p.clients.Get(t.Name()); ok {
-		return c
-	}
+// UsePgx makes Client use pgx driver and pgxpool instead of lib/pq.
+func (p *PostgresHelper) UsePgx() {
+	p.pgx = true
+}
 
-	database := p.database
+// SetOptions configures databases started for single tests, when postgres is not started by Init.
+// AddPostgresWith sets its options the same way.
+func (p *PostgresHelper) SetOptions(opts ...docker.PostgresOption) {
+	p.options = opts
+}
+
+// ResetOnCleanup truncates all tables except excludeTables in cleanup of each test using PostgresHelper
+// (Client, CaptureQueries, KillConnections etc.), so tests sharing the TestMain database start
+// from a clean state. Tests changing database only through the application call ResetAfter.
+func (p *PostgresHelper) ResetOnCleanup(excludeTables ...string) {
+	p.resetOnCleanup = true
+	p.resetExclude = excludeTables
+}
+
+// ResetAfter truncates all tables in test cleanup like ResetOnCleanup, without creating client.
+// Tables excluded by ResetOnCleanup are kept.
+func (p *PostgresHelper) ResetAfter(t *testing.T) {
+	database := p.testDatabase(t)
 	if database == nil {
-		d, err := docker.NewPostgres()
+		return
+	}
+	p.registerReset(t, database)
+}
+
+// registerReset truncates tables in test cleanup once per test. Reset uses own connection,
+// so it does not depend on client cleanup order.
+func (p *PostgresHelper) registerReset(t *testing.T, database *docker.Postgres) {
+	if _, ok := p.resets.Get(t.Name()); ok {
+		return
+	}
+	p.resets.Set(t.Name(), struct{}{})
+
+	exclude := p.resetExclude
+	t.Cleanup(func() {
+		p.resets.Delete(t.Name())
+		err := db.ResetDatabase(makeDbConfig(database).config(), exclude...)
 		if err != nil {
-			t.Errorf("new database error: %s", err)
-			return nil
+			t.Errorf("database reset error: %s", err)
 		}
-		t.Cleanup(func() {
-			err = d.Cleanup()
-			if err != nil {
-				t.Errorf("database cleanup error: %s", err)
-			}
-		})
+	})
+}
 
-		database = d
+func (p *PostgresHelper) Client(t *testing.T) DbClient {
+	if c, ok := p.clients.Get(t.Name()); ok {
+		return c
 	}
 
-	conf := db.Config{
-		Host:     database.Host(),
-		User:     database.User(),
-		Port:     database.Port(),
-		DbName:   database.Name(),
-		Password: database.Password(),
+	database := p.testDatabase(t)
+	if database == nil {
+		return nil
 	}
 
+	conf := makeDbConfig(database).config()
+
 	ctx := context.Background()
-	c, err := db.NewClientPg(ctx, t, conf)
+	newClient := db.NewClientPg
+	if p.pgx {
+		newClient = db.NewClientPgx
+	}
+	c, err := newClient(ctx, t, conf)
 	if err != nil {
 		t.Errorf("db new client error: %s", err)
 		return nil
@@ -58,3 +100,175 @@ func (p *PostgresHelper) Client(t *testing.T) DbClient {
 
 	return c
 }
+
+// CaptureQueries starts capturing statements executed by the application in test database.
+func (p *PostgresHelper) CaptureQueries(t *testing.T) *db.QueryCapture {
+	c := p.Client(t)
+	if c == nil {
+		return nil
+	}
+
+	capture, err := db.NewQueryCapture(t, c.DB(), p.testDatabase(t).StatementLog)
+	if err != nil {
+		t.Errorf("new query capture error: %s", err)
+		return nil
+	}
+	return capture
+}
+
+// testDatabase returns global database or starts a new one for single test.
+// Global database is reset in test cleanup if ResetOnCleanup is set.
+func (p *PostgresHelper) testDatabase(t *testing.T) *docker.Postgres {
+	if p.database != nil {
+		if p.resetOnCleanup {
+			p.registerReset(t, p.database)
+		}
+		return p.database
+	}
+	if d, ok := p.databases.Get(t.Name()); ok {
+		return d
+	}
+
+	d, err := docker.NewPostgres(p.options...)
+	if err != nil {
+		t.Errorf("new database error: %s", err)
+		return nil
+	}
+	p.databases.Set(t.Name(), d)
+
+	t.Cleanup(func() {
+		p.databases.Delete(t.Name())
+		err = d.Cleanup()
+		if err != nil {
+			t.Errorf("database cleanup error: %s", err)
+		}
+	})
+	return d
+}
+
+// Select scans all rows of query result to []T using sqlx rules, fails the test of client on error.
+func Select[T any](c DbClient, query string, args ...any) []T {
+	var dst []T
+	c.Select(&dst, query, args...)
+	return dst
+}
+
+// Get scans single row of query result to T using sqlx rules, fails the test of client on error or no rows.
+func Get[T any](c DbClient, query string, args ...any) T {
+	var dst T
+	c.Get(&dst, query, args...)
+	return dst
+}
+
+// CreateDatabase creates empty database in test postgres, dropped in test cleanup.
+func (p *PostgresHelper) CreateDatabase(t *testing.T, name string) DbConfig {
+	database := p.testDatabase(t)
+	if database == nil {
+		return DbConfig{}
+	}
+	conf := makeDbConfig(database)
+
+	err := db.CreateDatabase(conf.config(), name)
+	if err != nil {
+		t.Errorf("create database %s error: %s", name, err)
+		return DbConfig{}
+	}
+	t.Cleanup(func() {
+		err = db.DropDatabase(conf.config(), name)
+		if err != nil {
+			t.Errorf("drop database %s error: %s", name, err)
+		}
+	})
+
+	conf.Name = name
+	return conf
+}
+
+// CreateRole creates login role with grants in database of conf, dropped in test cleanup.
+// Grants are GRANT statement bodies, e.g. "SELECT ON ALL TABLES IN SCHEMA public".
+// Returns conf with role credentials.
+func (p *PostgresHelper) CreateRole(t *testing.T, conf DbConfig, name string, grants ...string) DbConfig {
+	password := name + "_password"
+
+	err := db.CreateRole(conf.config(), name, password, grants...)
+	if err != nil {
+		t.Errorf("create role %s error: %s", name, err)
+		return DbConfig{}
+	}
+	t.Cleanup(func() {
+		err = db.DropRole(conf.config(), name)
+		if err != nil {
+			t.Errorf("drop role %s error: %s", name, err)
+		}
+	})
+
+	conf.User = name
+	conf.Password = password
+	return conf
+}
+
+// Restart restarts test postgres keeping host port, returns when it accepts connections again.
+// Existing connections are broken, application must reconnect.
+func (p *PostgresHelper) Restart(t *testing.T) {
+	database := p.testDatabase(t)
+	if database == nil {
+		return
+	}
+
+	err := database.Restart(context.Background())
+	if err != nil {
+		t.Errorf("postgres restart error: %s", err)
+	}
+}
+
+// Pause freezes test postgres, so queries hang until returned resume func is called.
+// Postgres is resumed in test cleanup if resume is not called.
+func (p *PostgresHelper) Pause(t *testing.T) (resume func()) {
+	database := p.testDatabase(t)
+	if database == nil {
+		return func() {}
+	}
+
+	err := database.Pause(context.Background())
+	if err != nil {
+		t.Errorf("postgres pause error: %s", err)
+		return func() {}
+	}
+
+	var once gosync.Once
+	resume = func() {
+		once.Do(func() {
+			err := database.Unpause(context.Background())
+			if err != nil {
+				t.Errorf("postgres unpause error: %s", err)
+			}
+		})
+	}
+	t.Cleanup(resume)
+	return resume
+}
+
+// KillConnections terminates all application connections to test database, helper connections are kept.
+// Returns number of terminated connections.
+func (p *PostgresHelper) KillConnections(t *testing.T) int {
+	database := p.testDatabase(t)
+	if database == nil {
+		return 0
+	}
+
+	n, err := db.TerminateConnections(makeDbConfig(database).config())
+	if err != nil {
+		t.Errorf("postgres kill connections error: %s", err)
+	}
+	return n
+}
+
+func makeDbConfig(database *docker.Postgres) DbConfig {
+	return DbConfig{
+		Host:     database.Host(),
+		Name:     database.Name(),
+		User:     database.User(),
+		Port:     database.Port(),
+		Password: database.Password(),
+	}
+}
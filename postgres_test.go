@@ -0,0 +1,46 @@
+package steron
+
+import (
+	"reflect"
+	"testing"
+)
+
+type order struct {
+	ID     int64  `db:"id"`
+	Status string `db:"status"`
+}
+
+// fakeDbClient answers Select and Get with fixed rows, other methods are not used.
+type fakeDbClient struct {
+	DbClient
+
+	orders []order
+	query  string
+	args   []any
+}
+
+func (c *fakeDbClient) Select(dst any, query string, args ...any) {
+	c.query, c.args = query, args
+	*dst.(*[]order) = c.orders
+}
+
+func (c *fakeDbClient) Get(dst any, query string, args ...any) {
+	c.query, c.args = query, args
+	*dst.(*int) = len(c.orders)
+}
+
+func TestSelectGet(t *testing.T) {
+	c := &fakeDbClient{orders: []order{{ID: 1, Status: "paid"}, {ID: 2, Status: "new"}}}
+
+	orders := Select[order](c, "SELECT id, status FROM orders WHERE customer_id = $1", 42)
+	if !reflect.DeepEqual(orders, c.orders) {
+		t.Errorf("Select = %v, expected %v", orders, c.orders)
+	}
+	if c.query != "SELECT id, status FROM orders WHERE customer_id = $1" || !reflect.DeepEqual(c.args, []any{42}) {
+		t.Errorf("Select passed query %q args %v", c.query, c.args)
+	}
+
+	if count := Get[int](c, "SELECT count(*) FROM orders"); count != 2 {
+		t.Errorf("Get = %d, expected 2", count)
+	}
+}
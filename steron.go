@@ -2,27 +2,53 @@ package steron
 
 import (
 	"context"
+	"crypto/tls"
 	"database/sql"
 	"flag"
 	"fmt"
+	"io/fs"
 	"net/http"
+	"testing"
 	"time"
 
 	"github.com/IBM/sarama"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jmoiron/sqlx"
 
+	"github.com/FluorescentTouch/testosteron/db"
 	"github.com/FluorescentTouch/testosteron/docker"
+	"github.com/FluorescentTouch/testosteron/http/server"
 )
 
 type WebServer interface {
 	HandleFunc(pattern string, handler http.HandlerFunc)
 	Addr() string
 	Cleanup()
+
+	Requests() []server.Request
+	AssertCalled(t *testing.T, method, path string, times int)
+	AssertNotCalled(t *testing.T, method, path string)
+
+	On(method, path string) *server.Stub
+	ScenarioState(scenario string) string
+	SetScenarioState(scenario, state string)
+	Strict()
+	Override(t *testing.T, pattern string, handler http.HandlerFunc)
+	Certificates() *server.Certificates
+}
+
+type OpenAPIServer interface {
+	WebServer
+
+	HandleOperation(operation string, handler http.HandlerFunc)
+	RespondOperation(operation string, status int)
 }
 
 type WebClient interface {
 	Do(req *http.Request) *http.Response
 	Get(url string) *http.Response
 	GetJSON(url string, dst any)
+	SetTLSConfig(conf *tls.Config)
 }
 
 type KafkaClient interface {
@@ -33,7 +59,32 @@ type KafkaClient interface {
 
 type DbClient interface {
 	DB() *sql.DB
-	Migrate(migrateDir string) error
+	Sqlx() *sqlx.DB
+	Select(dst any, query string, args ...any)
+	Get(dst any, query string, args ...any)
+	PgxPool() *pgxpool.Pool
+	Migrate(migrateDir string, opts ...db.MigrateOption) error
+	MigrateFS(fsys fs.FS, opts ...db.MigrateOption) error
+	MigrateTo(fsys fs.FS, version int64, opts ...db.MigrateOption) error
+	MigrateDown(fsys fs.FS, n int, opts ...db.MigrateOption) error
+	MigrateRoundTrip(fsys fs.FS, opts ...db.MigrateOption) error
+	Reset(excludeTables ...string) error
+	Snapshot(name string) error
+	Restore(name string) error
+	TrackChanges(tables ...string) error
+	Changes() ([]db.Change, error)
+
+	Listen(channel string) (*db.Subscription, error)
+	Notify(channel, payload string) error
+	ExpectNotification(channel string, matcher db.PayloadMatcher, timeout time.Duration)
+
+	AssertRowCount(table, where string, n int, args ...any)
+	AssertRow(table, where string, expected map[string]any, args ...any)
+	EventuallyRow(timeout time.Duration, table, where string, expected map[string]any, args ...any)
+	ExplainAssert(query string, args []any, rules ...db.PlanRule)
+	AssertSchema(goldenFile string)
+
+	Insert(table string, overrides map[string]any) map[string]any
 }
 
 type DbConfig struct {
@@ -44,6 +95,26 @@ type DbConfig struct {
 	Password string
 }
 
+// DSN returns keyword/value connection string for lib/pq.
+func (c DbConfig) DSN() string {
+	return c.config().String()
+}
+
+// URL returns postgres:// connection URL, accepted by pgx and most other drivers.
+func (c DbConfig) URL() string {
+	return c.config().URL()
+}
+
+func (c DbConfig) config() db.Config {
+	return db.Config{
+		Host:     c.Host,
+		User:     c.User,
+		Port:     c.Port,
+		DbName:   c.Name,
+		Password: c.Password,
+	}
+}
+
 type Config struct {
 	postgresConfig DbConfig
 	kafkaBrokers   []string
@@ -58,6 +129,7 @@ func (c Config) PgConfig() DbConfig {
 }
 
 func Init(options ...option) (Config, error) {
+	db.RegisterFlags()
 	flag.Parse()
 
 	for _, o := range options {
@@ -84,19 +156,22 @@ func AddKafka(h *Helper) error {
 }
 
 func AddPostgres(h *Helper) error {
-	database, err := docker.NewPostgres()
-	if err != nil {
-		return fmt.Errorf("postgres init error: %w", err)
-	}
-	h.postgres.database = database
-	h.cfg.postgresConfig = DbConfig{
-		Host:     database.Host(),
-		Name:     database.Name(),
-		User:     database.User(),
-		Port:     database.Port(),
-		Password: database.Password(),
+	return AddPostgresWith()(h)
+}
+
+// AddPostgresWith starts postgres configured by opts: image, credentials, settings, extensions.
+// The same opts are used for databases started for single tests, see PostgresHelper.SetOptions.
+func AddPostgresWith(opts ...docker.PostgresOption) option {
+	return func(h *Helper) error {
+		database, err := docker.NewPostgres(opts...)
+		if err != nil {
+			return fmt.Errorf("postgres init error: %w", err)
+		}
+		h.postgres.database = database
+		h.postgres.options = opts
+		h.cfg.postgresConfig = makeDbConfig(database)
+		return nil
 	}
-	return nil
 }
 
 func HTTP() *HTTPHelper {